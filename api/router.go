@@ -4,6 +4,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/messaging-go-service/internal/controller"
 	"github.com/messaging-go-service/internal/repository"
+	"github.com/messaging-go-service/internal/service"
 	"github.com/messaging-go-service/middleware"
 	"gorm.io/gorm"
 )
@@ -15,12 +16,47 @@ func Routes(db *gorm.DB) *mux.Router {
 	userRepo := repository.NewUserRepository(db)
 	notificationRepo := repository.NewNotificationRepository(db)
 	conversationRepo := repository.NewConversationRepository(db)
+	messageRepo := repository.NewMessageRepositoryImpl(db)
+	contactRepo := repository.NewContactRepository(db)
+	blockRepo := repository.NewBlockRepository(db)
+	reportRepo := repository.NewReportRepository(db)
+	inviteRepo := repository.NewInviteRepository(db)
+	channelRepo := repository.NewChannelRepository(db)
+	pinRepo := repository.NewPinRepository(db)
+	scheduledMessageRepo := repository.NewScheduledMessageRepository(db)
+
+	// Init services
+	mediaStorage := service.NewLocalMediaStorage()
+	displayNameResolver := service.NewDisplayNameResolver(userRepo, contactRepo)
+	policy := service.NewPolicy(blockRepo, conversationRepo, userRepo, contactRepo)
+	notificationService := service.NewNotificationService(notificationRepo, userRepo, conversationRepo)
+	messageService := service.NewMessageService(messageRepo, notificationService)
+	conversationService := service.NewConversationService(conversationRepo, userRepo, policy, notificationService, messageService, mediaStorage)
+	inviteService := service.NewInviteService(inviteRepo, conversationRepo, channelRepo, conversationService)
+	channelService := service.NewChannelService(conversationRepo, channelRepo, policy, messageService)
+	presenceService := service.NewPresenceService(userRepo, policy)
+	receiptService := service.NewReceiptService(conversationRepo, messageRepo)
+	pinService := service.NewPinService(pinRepo, messageRepo, conversationRepo, conversationService, channelService)
+	scheduledMessageService := service.NewScheduledMessageService(scheduledMessageRepo, conversationRepo, policy, conversationService, messageService)
+	searchService := service.NewSearchService(messageRepo, userRepo)
+	userService := service.NewUserService(userRepo)
+	mediaService := service.NewMediaService(messageRepo, conversationRepo, channelService)
 
 	// Init controllers
-	authController := controller.NewAuthController(userRepo)
-	userController := controller.NewUserController(userRepo)
+	authController := controller.NewAuthController(userRepo, userService)
+	userController := controller.NewUserController(userRepo, userService, presenceService)
 	notificationController := controller.NewNotificationController(notificationRepo)
-	conversationController := controller.NewConversationController(conversationRepo, userRepo)
+	conversationController := controller.NewConversationController(conversationRepo, userRepo, blockRepo, reportRepo, displayNameResolver, policy, conversationService, messageService)
+	contactController := controller.NewContactController(contactRepo, userRepo)
+	blockController := controller.NewBlockController(blockRepo, userRepo)
+	messageController := controller.NewMessageController(messageRepo, conversationRepo, userRepo, mediaStorage, policy, conversationService, messageService)
+	inviteController := controller.NewInviteController(inviteService)
+	channelController := controller.NewChannelController(channelService)
+	pinController := controller.NewPinController(pinService)
+	receiptController := controller.NewReceiptController(receiptService)
+	scheduledMessageController := controller.NewScheduledMessageController(scheduledMessageService)
+	searchController := controller.NewSearchController(searchService)
+	mediaController := controller.NewMediaController(mediaService)
 
 	// Init routers
 	authRouter := router.PathPrefix("/api/auth").Subrouter()
@@ -31,22 +67,103 @@ func Routes(db *gorm.DB) *mux.Router {
 	userRouter := router.PathPrefix("/api/user").Subrouter()
 	userRouter.Use(middleware.CheckAuth)
 	userRouter.HandleFunc("", userController.CreateUser).Methods("POST")
+	userRouter.HandleFunc("/privacy", userController.UpdatePrivacy).Methods("PUT")
+	userRouter.HandleFunc("/username", userController.ChangeUsername).Methods("PUT")
+	userRouter.HandleFunc("/username/history", userController.GetUsernameHistory).Methods("GET")
+	userRouter.HandleFunc("/handle/{handle}", userController.GetUserByHandle).Methods("GET")
 	userRouter.HandleFunc("/{id}", userController.UpdateUser).Methods("PUT")
 	userRouter.HandleFunc("/search", userController.SearchUsers).Methods("GET")
+	userRouter.HandleFunc("/{id}/presence", userController.GetPresence).Methods("GET")
 	userRouter.HandleFunc("/{id}", userController.GetUserDetail).Methods("GET")
 
+	contactRouter := router.PathPrefix("/api/contact").Subrouter()
+	contactRouter.Use(middleware.CheckAuth)
+	contactRouter.HandleFunc("", contactController.GetContacts).Methods("GET")
+	contactRouter.HandleFunc("", contactController.AddContact).Methods("POST")
+	contactRouter.HandleFunc("/import", contactController.ImportContacts).Methods("POST")
+	contactRouter.HandleFunc("/{id}", contactController.UpdateContact).Methods("PUT")
+	contactRouter.HandleFunc("/{id}", contactController.DeleteContact).Methods("DELETE")
+
+	blockRouter := router.PathPrefix("/api/block").Subrouter()
+	blockRouter.Use(middleware.CheckAuth)
+	blockRouter.HandleFunc("", blockController.GetBlockedUsers).Methods("GET")
+	blockRouter.HandleFunc("", blockController.BlockUser).Methods("POST")
+	blockRouter.HandleFunc("/{user_id}", blockController.UnblockUser).Methods("DELETE")
+
 	notificationRouter := router.PathPrefix("/api/notification").Subrouter()
-	notificationRouter.HandleFunc("/list/{user_id}", notificationController.GetNotificationsByUser).Methods("GET")
+	notificationRouter.Use(middleware.CheckAuth)
+	notificationRouter.HandleFunc("/list", notificationController.GetNotificationsByUser).Methods("GET")
 
 	conversationRouter := router.PathPrefix("/api/conversation").Subrouter()
 	conversationRouter.Use(middleware.CheckAuth)
 	conversationRouter.HandleFunc("", conversationController.AddConversation).Methods("POST")
+	conversationRouter.HandleFunc("/direct", conversationController.GetOrCreateDirectConversation).Methods("POST")
+	conversationRouter.HandleFunc("/pins", conversationController.ReorderPins).Methods("PUT")
+	conversationRouter.HandleFunc("/requests", conversationController.GetRequestConversations).Methods("GET")
+	conversationRouter.HandleFunc("/unread", receiptController.GetUnreadCounts).Methods("GET")
+	conversationRouter.HandleFunc("/scheduled", scheduledMessageController.GetScheduledMessages).Methods("GET")
+	conversationRouter.HandleFunc("/scheduled/{scheduled_id}", scheduledMessageController.UpdateScheduledMessage).Methods("PUT")
+	conversationRouter.HandleFunc("/scheduled/{scheduled_id}", scheduledMessageController.CancelScheduledMessage).Methods("DELETE")
+	conversationRouter.HandleFunc("/{id}/request/accept", conversationController.AcceptRequest).Methods("POST")
+	conversationRouter.HandleFunc("/{id}/request/decline", conversationController.DeclineRequest).Methods("POST")
+	conversationRouter.HandleFunc("/{id}/request/report", conversationController.ReportRequest).Methods("POST")
+	conversationRouter.HandleFunc("/{id}/invite", inviteController.CreateInviteLink).Methods("POST")
+	conversationRouter.HandleFunc("/{id}/invite", inviteController.GetInviteLinks).Methods("GET")
+	conversationRouter.HandleFunc("/{id}/invite/{invite_id}", inviteController.RevokeInviteLink).Methods("DELETE")
+	conversationRouter.HandleFunc("/{id}/join-request", inviteController.GetJoinRequests).Methods("GET")
+	conversationRouter.HandleFunc("/{id}/join-request/{request_id}/approve", inviteController.ApproveJoinRequest).Methods("POST")
+	conversationRouter.HandleFunc("/{id}/join-request/{request_id}/decline", inviteController.DeclineJoinRequest).Methods("POST")
+	conversationRouter.HandleFunc("/{id}/read", receiptController.MarkRead).Methods("POST")
+	conversationRouter.HandleFunc("/{id}/delivered", receiptController.MarkDelivered).Methods("POST")
+	conversationRouter.HandleFunc("/{id}/pinned", pinController.GetPinnedMessages).Methods("GET")
+	conversationRouter.HandleFunc("/{id}/scheduled", scheduledMessageController.ScheduleMessage).Methods("POST")
+	conversationRouter.HandleFunc("/{id}/settings", conversationController.UpdateSettings).Methods("PUT")
+	conversationRouter.HandleFunc("/{id}/avatar", conversationController.UpdateAvatar).Methods("PUT")
+	conversationRouter.HandleFunc("/{id}/avatar", conversationController.RemoveAvatar).Methods("DELETE")
+	conversationRouter.HandleFunc("/{id}/leave", conversationController.LeaveConversation).Methods("POST")
+	conversationRouter.HandleFunc("/{id}/participant/{user_id}", conversationController.RemoveParticipant).Methods("DELETE")
+	conversationRouter.HandleFunc("/{id}/participant/{user_id}/role", conversationController.ChangeParticipantRole).Methods("PUT")
+	conversationRouter.HandleFunc("/{id}/owner", conversationController.TransferOwnership).Methods("POST")
+	conversationRouter.HandleFunc("/{id}", conversationController.UpdateConversation).Methods("PUT")
 	conversationRouter.HandleFunc("/{id}", conversationController.DeleteConversation).Methods("DELETE")
 	conversationRouter.HandleFunc("/{id}", conversationController.GetConversationDetail).Methods("GET")
 	conversationRouter.HandleFunc("/participant", conversationController.AddParticipant).Methods("POST")
 	conversationRouter.HandleFunc("/message", conversationController.AddMessage).Methods("POST")
+	conversationRouter.HandleFunc("/message/voice", messageController.AddVoiceMessage).Methods("POST")
+	conversationRouter.HandleFunc("/message/search", searchController.SearchMessages).Methods("GET")
+	conversationRouter.HandleFunc("/message/{id}/played", messageController.MarkPlayed).Methods("POST")
+	conversationRouter.HandleFunc("/message/{id}/played", messageController.GetPlays).Methods("GET")
+	conversationRouter.HandleFunc("/message/contact", messageController.AddContactMessage).Methods("POST")
+	conversationRouter.HandleFunc("/message/{id}/contact", messageController.ExportContact).Methods("GET")
+	conversationRouter.HandleFunc("/message/{id}/contact/chat", messageController.StartChatFromContact).Methods("POST")
+	conversationRouter.HandleFunc("/message/{id}/seen", receiptController.GetSeenBy).Methods("GET")
+	conversationRouter.HandleFunc("/message/{id}/status", receiptController.GetMessageStatus).Methods("GET")
+	conversationRouter.HandleFunc("/message/{id}/pin", pinController.PinMessage).Methods("POST")
+	conversationRouter.HandleFunc("/message/{id}/pin", pinController.UnpinMessage).Methods("DELETE")
+	conversationRouter.HandleFunc("/message/{id}", messageController.DeleteMessage).Methods("DELETE")
 	conversationRouter.HandleFunc("/message/{conversation_id}", conversationController.RetrieveMessages).Methods("GET")
 	conversationRouter.HandleFunc("/all/{user_id}", conversationController.GetConversationsByUserID).Methods("GET")
 
+	channelRouter := router.PathPrefix("/api/channel").Subrouter()
+	channelRouter.Use(middleware.CheckAuth)
+	channelRouter.HandleFunc("", channelController.CreateChannel).Methods("POST")
+	channelRouter.HandleFunc("/search", channelController.SearchChannels).Methods("GET")
+	channelRouter.HandleFunc("/subscribed", channelController.GetSubscribedChannels).Methods("GET")
+	channelRouter.HandleFunc("/{id}", channelController.GetChannel).Methods("GET")
+	channelRouter.HandleFunc("/{id}/subscribe", channelController.Subscribe).Methods("POST")
+	channelRouter.HandleFunc("/{id}/subscribe", channelController.Unsubscribe).Methods("DELETE")
+	channelRouter.HandleFunc("/{id}/post", channelController.GetPosts).Methods("GET")
+	channelRouter.HandleFunc("/{id}/post", channelController.Publish).Methods("POST")
+	channelRouter.HandleFunc("/{id}/view", channelController.RecordViews).Methods("POST")
+
+	inviteRouter := router.PathPrefix("/api/invite").Subrouter()
+	inviteRouter.Use(middleware.CheckAuth)
+	inviteRouter.HandleFunc("/{token}", inviteController.PreviewInvite).Methods("GET")
+	inviteRouter.HandleFunc("/{token}/join", inviteController.JoinByInvite).Methods("POST")
+
+	mediaRouter := router.PathPrefix("/media").Subrouter()
+	mediaRouter.Use(middleware.CheckAuth)
+	mediaRouter.HandleFunc("/{name}", mediaController.GetMedia).Methods("GET")
+
 	return router
 }
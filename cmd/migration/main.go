@@ -2,9 +2,15 @@ package main
 
 import (
 	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/messaging-go-service/config"
 	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/service"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -14,14 +20,204 @@ func main() {
 		log.Fatal("Database connection is nil")
 	}
 
+	// Re-adding a member used to create a second row. Merge them into the
+	// oldest one so the unique membership index can be created.
+	// Databases created before messages were migrated have no messages table.
+	if config.Database.Migrator().HasTable(&model.Participant{}) {
+		var dedupe []string
+		if config.Database.Migrator().HasTable(&model.Message{}) {
+			dedupe = append(dedupe, `UPDATE messages SET participant_id = keep.id
+			FROM participants dup, (SELECT conversation_id, user_id, MIN(id) AS id FROM participants GROUP BY conversation_id, user_id) AS keep
+			WHERE messages.participant_id = dup.id AND dup.conversation_id = keep.conversation_id AND dup.user_id = keep.user_id AND dup.id <> keep.id`)
+		}
+		dedupe = append(dedupe, `DELETE FROM participants USING participants keep
+			WHERE participants.conversation_id = keep.conversation_id AND participants.user_id = keep.user_id AND participants.id > keep.id`)
+		for _, statement := range dedupe {
+			if err := config.Database.Exec(statement).Error; err != nil {
+				log.Fatalf("Failed to merge duplicate participants: %v", err)
+			}
+		}
+	}
+
+	// Emails used to be unique only by exact match. Duplicates differing in
+	// case need a person to merge them before the unique index is created.
+	if config.Database.Migrator().HasTable(&model.User{}) {
+		var duplicateEmails int64
+		if err := config.Database.Raw(`SELECT count(*) FROM (SELECT lower(email) FROM users
+			WHERE deleted_at IS NULL AND email <> '' GROUP BY lower(email) HAVING count(*) > 1) AS duplicates`).Scan(&duplicateEmails).Error; err != nil {
+			log.Fatalf("Failed to check duplicate emails: %v", err)
+		}
+		if duplicateEmails > 0 {
+			log.Fatalf("%d email addresses are registered more than once; merge those users before migrating", duplicateEmails)
+		}
+	}
+
 	if err := config.Database.AutoMigrate(
 		&model.User{},
 		&model.Conversation{},
 		&model.Participant{},
+		&model.Message{},
+		&model.Media{},
+		&model.MessagePlay{},
+		&model.SharedContact{},
 		&model.Notification{},
+		&model.Contact{},
+		&model.Block{},
+		&model.Report{},
+		&model.InviteLink{},
+		&model.JoinRequest{},
+		&model.ChannelSubscription{},
+		&model.MessageView{},
+		&model.PinnedMessage{},
+		&model.ScheduledMessage{},
+		&model.UsernameHistory{},
 	); err != nil {
 		log.Fatalf("Failed to auto-migrate database: %v", err)
 	}
 
+	// Usernames used to be stored as typed, unique only by exact match, and
+	// must now be normalized and unique regardless of case before the unique
+	// index is created.
+	if err := normalizeUsernames(); err != nil {
+		log.Fatalf("Failed to normalize usernames: %v", err)
+	}
+
+	schema := []string{
+		// Message search matches against a generated tsvector, which gorm
+		// cannot declare.
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (to_tsvector('simple', coalesce(text, ''))) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_search ON messages USING GIN (search_vector)`,
+		// User search matches usernames and saved contact names by trigram
+		// similarity and emails by prefix, all case-insensitively.
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING GIN (lower(username) gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_contacts_saved_name_trgm ON contacts USING GIN (lower(saved_name) gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_users_email_prefix ON users (lower(email) text_pattern_ops)`,
+		// Usernames and emails are unique regardless of case.
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users (lower(username)) WHERE deleted_at IS NULL`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (lower(email)) WHERE deleted_at IS NULL AND email <> ''`,
+		`CREATE INDEX IF NOT EXISTS idx_username_histories_username_lower ON username_histories (lower(username), changed_at)`,
+	}
+	for _, statement := range schema {
+		if err := config.Database.Exec(statement).Error; err != nil {
+			log.Fatalf("Failed to update schema: %v", err)
+		}
+	}
+
+	backfills := []string{
+		// The inbox orders by the last message pointer, which was not kept before.
+		`UPDATE conversations SET last_message_id = latest.id, last_message_at = latest.created_at
+		FROM (SELECT DISTINCT ON (conversation_id) conversation_id, id, created_at FROM messages WHERE deleted_at IS NULL ORDER BY conversation_id, id DESC) AS latest
+		WHERE conversations.id = latest.conversation_id AND conversations.last_message_id IS NULL`,
+		`UPDATE participants SET joined_at = created_at WHERE joined_at IS NULL`,
+		// Seen-by lists need a read time for watermarks moved before it was kept.
+		`UPDATE participants SET last_read_at = updated_at WHERE last_read_at IS NULL AND last_read_message_id > 0`,
+		// Messages read before delivery was tracked were delivered too.
+		`UPDATE participants SET last_delivered_message_id = last_read_message_id WHERE last_delivered_message_id < last_read_message_id`,
+		`UPDATE conversations SET delivered_message_id = watermarks.delivered, read_message_id = watermarks.read
+		FROM (SELECT conversation_id, MIN(last_delivered_message_id) AS delivered, MIN(last_read_message_id) AS read
+			FROM participants WHERE request_status = 'accepted' AND left_at IS NULL AND deleted_at IS NULL GROUP BY conversation_id) AS watermarks
+		WHERE conversations.id = watermarks.conversation_id AND conversations.delivered_message_id < watermarks.delivered`,
+		// Group creators predate roles and become the owners of their groups.
+		`UPDATE participants SET role = 'owner' FROM conversations WHERE participants.conversation_id = conversations.id AND participants.user_id = conversations.user_id AND conversations.kind <> 'direct' AND participants.role = 'member'`,
+	}
+	for _, backfill := range backfills {
+		if err := config.Database.Exec(backfill).Error; err != nil {
+			log.Fatalf("Failed to backfill database: %v", err)
+		}
+	}
+
+	// Email hashes are keyed with EMAIL_HASH_KEY, which SQL cannot compute.
+	// Rehash users registered before hashes existed or were keyed, and every
+	// user when the key changes.
+	if os.Getenv("EMAIL_HASH_KEY") == "" {
+		log.Println("EMAIL_HASH_KEY is not set, email hashes are not keyed")
+	}
+	var users []model.User
+	rehash := config.Database.Select("id", "email", "email_hash").FindInBatches(&users, 500, func(tx *gorm.DB, batch int) error {
+		for _, user := range users {
+			if hash := model.HashEmail(user.Email); hash != user.EmailHash {
+				if err := config.Database.Model(&model.User{}).Where("id = ?", user.ID).UpdateColumn("email_hash", hash).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if rehash.Error != nil {
+		log.Fatalf("Failed to backfill email hashes: %v", rehash.Error)
+	}
+
 	log.Println("Database migration completed successfully.")
 }
+
+// normalizeUsernames stores every username in its normalized form. The oldest
+// user keeps a name; a later user whose name is taken regardless of case, or
+// is not a valid username at all, is renamed to it, or to "user", followed by
+// their id. Renamed users find their old name in their username history and
+// may pick a new one right away.
+func normalizeUsernames() error {
+	var users []model.User
+	if err := config.Database.Select("id", "username").Order("id").Find(&users).Error; err != nil {
+		return err
+	}
+
+	taken := make(map[string]bool, len(users))
+	usernames := make([]string, len(users))
+	var renamed []int
+	for i, user := range users {
+		username, err := service.NormalizeUsername(user.Username)
+		if err != nil || taken[strings.ToLower(username)] {
+			renamed = append(renamed, i)
+			continue
+		}
+		taken[strings.ToLower(username)] = true
+		usernames[i] = username
+	}
+
+	for _, i := range renamed {
+		base, err := service.NormalizeUsername(users[i].Username)
+		if err != nil {
+			base = "user"
+		}
+		suffix := "_" + strconv.Itoa(users[i].ID)
+		for attempt := 1; ; attempt++ {
+			username := service.UsernameWithSuffix(base, suffix)
+			if _, err := service.NormalizeUsername(username); err != nil {
+				// Shortening the name made it invalid, such as by leaving only
+				// letters that look Latin.
+				base = "user"
+				continue
+			}
+			if !taken[strings.ToLower(username)] {
+				taken[strings.ToLower(username)] = true
+				usernames[i] = username
+				break
+			}
+			suffix = "_" + strconv.Itoa(users[i].ID) + "_" + strconv.Itoa(attempt)
+		}
+	}
+
+	now := time.Now()
+	changed := 0
+	for i, user := range users {
+		if usernames[i] == user.Username {
+			continue
+		}
+		err := config.Database.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&model.User{}).Where("id = ?", user.ID).UpdateColumn("username", usernames[i]).Error; err != nil {
+				return err
+			}
+			return tx.Create(&model.UsernameHistory{UserID: user.ID, Username: user.Username, ChangedAt: now}).Error
+		})
+		if err != nil {
+			return err
+		}
+		changed++
+	}
+	if changed > 0 {
+		log.Printf("Normalized %d usernames", changed)
+	}
+	return nil
+}
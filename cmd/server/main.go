@@ -32,12 +32,15 @@ func main() {
 	}
 
 	go service.RecentHub.Run()
+	go service.RunScheduler()
+	go service.RunExpirySweeper()
 
 	router := router.Routes(db)
 	protectedRoutes := EnableCors(router)
 
 	http.Handle("/", protectedRoutes)
 	http.HandleFunc("/ws", service.HandleWebSocket)
+	http.HandleFunc("/ws/channel", service.HandleChannelWebSocket)
 
 	log.Println("Server is listening on port 3200")
 	log.Fatal(http.ListenAndServe("0.0.0.0:3200", nil))
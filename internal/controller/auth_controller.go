@@ -8,8 +8,9 @@ import (
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gorilla/mux"
-	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/dto"
 	"github.com/messaging-go-service/internal/repository"
+	"github.com/messaging-go-service/internal/service"
 	httputil "github.com/messaging-go-service/pkg/http"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -22,11 +23,13 @@ type AuthController interface {
 
 type AuthControllerImpl struct {
 	UserRepository repository.UserRepository
+	UserService    service.UserService
 }
 
-func NewAuthController(userRepository repository.UserRepository) AuthController {
+func NewAuthController(userRepository repository.UserRepository, userService service.UserService) AuthController {
 	return &AuthControllerImpl{
 		UserRepository: userRepository,
+		UserService:    userService,
 	}
 }
 
@@ -42,30 +45,18 @@ func (c *AuthControllerImpl) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(requestBody.Password), bcrypt.DefaultCost)
+	newUser, err := c.UserService.Register(context.Background(), requestBody.Username, requestBody.Email, requestBody.Password)
 	if err != nil {
-		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error hashing password"})
-		return
-	}
-
-	newUser := model.User{
-		Username:       requestBody.Username,
-		Email:          requestBody.Email,
-		Password:       string(hashedPwd),
-		ProfilePicture: "",
-	}
-
-	if err := c.UserRepository.CreateUser(context.Background(), &newUser); err != nil {
-		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error creating user"})
+		writeUserError(w, err, "Error creating user")
 		return
 	}
 
 	response := struct {
-		Message string     `json:"message"`
-		Data    model.User `json:"data"`
+		Message string       `json:"message"`
+		Data    dto.SelfUser `json:"data"`
 	}{
 		Message: "New user has been registered",
-		Data:    newUser,
+		Data:    dto.NewSelfUser(newUser),
 	}
 
 	httputil.WriteResponse(w, http.StatusOK, response)
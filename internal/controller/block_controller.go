@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/messaging-go-service/internal/dto"
+	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/repository"
+	"github.com/messaging-go-service/middleware"
+	httputil "github.com/messaging-go-service/pkg/http"
+)
+
+type BlockController interface {
+	BlockUser(w http.ResponseWriter, r *http.Request)
+	UnblockUser(w http.ResponseWriter, r *http.Request)
+	GetBlockedUsers(w http.ResponseWriter, r *http.Request)
+}
+
+type BlockControllerImpl struct {
+	BlockRepository repository.BlockRepository
+	UserRepository  repository.UserRepository
+}
+
+func NewBlockController(blockRepo repository.BlockRepository, userRepo repository.UserRepository) BlockController {
+	return &BlockControllerImpl{
+		BlockRepository: blockRepo,
+		UserRepository:  userRepo,
+	}
+}
+
+func (c *BlockControllerImpl) BlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	var requestBody struct {
+		UserID int `json:"user_id"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if requestBody.UserID == userID {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "You cannot block yourself"})
+		return
+	}
+
+	if _, err := c.UserRepository.GetUserByID(context.Background(), requestBody.UserID); err != nil {
+		httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	newBlock := model.Block{
+		UserID:        userID,
+		BlockedUserID: requestBody.UserID,
+	}
+
+	if err := c.BlockRepository.CreateBlock(context.Background(), &newBlock); err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error blocking user"})
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "User has been blocked",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *BlockControllerImpl) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	vars := mux.Vars(r)
+	blockedUserIDstr := vars["user_id"]
+
+	blockedUserID, err := strconv.Atoi(blockedUserIDstr)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid user id"})
+		return
+	}
+
+	if err := c.BlockRepository.DeleteBlock(context.Background(), userID, blockedUserID); err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error unblocking user"})
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "User has been unblocked",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *BlockControllerImpl) GetBlockedUsers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	pagination := httputil.ReadPagination(r)
+
+	blocks, total, err := c.BlockRepository.GetBlocksByUserID(context.Background(), userID, pagination.Limit, pagination.Offset())
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error retrieving blocked users"})
+		return
+	}
+	pagination.Total = total
+
+	response := struct {
+		Message    string              `json:"message"`
+		Data       []dto.Block         `json:"data"`
+		Pagination httputil.Pagination `json:"pagination"`
+	}{
+		Message:    "Blocked users have been retrieved",
+		Data:       dto.NewBlocks(blocks),
+		Pagination: pagination,
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,305 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/messaging-go-service/internal/dto"
+	"github.com/messaging-go-service/internal/service"
+	"github.com/messaging-go-service/middleware"
+	httputil "github.com/messaging-go-service/pkg/http"
+)
+
+const maxViewBatchSize = 100
+
+type ChannelController interface {
+	CreateChannel(w http.ResponseWriter, r *http.Request)
+	SearchChannels(w http.ResponseWriter, r *http.Request)
+	GetSubscribedChannels(w http.ResponseWriter, r *http.Request)
+	GetChannel(w http.ResponseWriter, r *http.Request)
+	Subscribe(w http.ResponseWriter, r *http.Request)
+	Unsubscribe(w http.ResponseWriter, r *http.Request)
+	GetPosts(w http.ResponseWriter, r *http.Request)
+	Publish(w http.ResponseWriter, r *http.Request)
+	RecordViews(w http.ResponseWriter, r *http.Request)
+}
+
+type ChannelControllerImpl struct {
+	ChannelService service.ChannelService
+}
+
+func NewChannelController(channelService service.ChannelService) ChannelController {
+	return &ChannelControllerImpl{
+		ChannelService: channelService,
+	}
+}
+
+func (c *ChannelControllerImpl) CreateChannel(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	var requestBody struct {
+		Title  string `json:"title"`
+		Public bool   `json:"public"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	title := strings.TrimSpace(requestBody.Title)
+	if title == "" {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Channel title is required"})
+		return
+	}
+
+	channel, err := c.ChannelService.CreateChannel(context.Background(), userID, title, requestBody.Public)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error creating channel"})
+		return
+	}
+
+	response := struct {
+		Message string           `json:"message"`
+		Data    dto.Conversation `json:"data"`
+	}{
+		Message: "Channel has been created",
+		Data:    dto.NewConversation(channel),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ChannelControllerImpl) SearchChannels(w http.ResponseWriter, r *http.Request) {
+	pagination := httputil.ReadPagination(r)
+
+	channels, total, err := c.ChannelService.Search(context.Background(), strings.TrimSpace(r.URL.Query().Get("q")), pagination.Limit, pagination.Offset())
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error searching channels"})
+		return
+	}
+	pagination.Total = total
+
+	response := struct {
+		Message    string              `json:"message"`
+		Data       []dto.Conversation  `json:"data"`
+		Pagination httputil.Pagination `json:"pagination"`
+	}{
+		Message:    "Channels have been retrieved",
+		Data:       dto.NewConversations(channels),
+		Pagination: pagination,
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ChannelControllerImpl) GetSubscribedChannels(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	pagination := httputil.ReadPagination(r)
+
+	channels, total, err := c.ChannelService.GetSubscribed(context.Background(), userID, pagination.Limit, pagination.Offset())
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error retrieving channels"})
+		return
+	}
+	pagination.Total = total
+
+	response := struct {
+		Message    string              `json:"message"`
+		Data       []dto.Conversation  `json:"data"`
+		Pagination httputil.Pagination `json:"pagination"`
+	}{
+		Message:    "Channels have been retrieved",
+		Data:       dto.NewConversations(channels),
+		Pagination: pagination,
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ChannelControllerImpl) GetChannel(w http.ResponseWriter, r *http.Request) {
+	channelID, ok := readConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	channel, err := c.ChannelService.GetReadableChannel(context.Background(), channelID, userID)
+	if err != nil {
+		writeChannelError(w, err, "Error retrieving channel")
+		return
+	}
+
+	response := struct {
+		Message string           `json:"message"`
+		Data    dto.Conversation `json:"data"`
+	}{
+		Message: "Channel has been retrieved",
+		Data:    dto.NewConversation(channel),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ChannelControllerImpl) Subscribe(w http.ResponseWriter, r *http.Request) {
+	channelID, ok := readConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	if err := c.ChannelService.Subscribe(context.Background(), channelID, userID); err != nil {
+		writeChannelError(w, err, "Error subscribing to channel")
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "You have subscribed to the channel",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ChannelControllerImpl) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	channelID, ok := readConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	if err := c.ChannelService.Unsubscribe(context.Background(), channelID, userID); err != nil {
+		writeChannelError(w, err, "Error unsubscribing from channel")
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "You have unsubscribed from the channel",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ChannelControllerImpl) GetPosts(w http.ResponseWriter, r *http.Request) {
+	channelID, ok := readConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	pagination := httputil.ReadPagination(r)
+
+	posts, total, err := c.ChannelService.GetPosts(context.Background(), channelID, userID, pagination.Limit, pagination.Offset())
+	if err != nil {
+		writeChannelError(w, err, "Error retrieving posts")
+		return
+	}
+	pagination.Total = total
+
+	response := struct {
+		Message    string              `json:"message"`
+		Data       []dto.Message       `json:"data"`
+		Pagination httputil.Pagination `json:"pagination"`
+	}{
+		Message:    "Posts have been retrieved",
+		Data:       dto.NewMessages(posts),
+		Pagination: pagination,
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ChannelControllerImpl) Publish(w http.ResponseWriter, r *http.Request) {
+	channelID, ok := readConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	var requestBody struct {
+		Text string `json:"text"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil || strings.TrimSpace(requestBody.Text) == "" {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	post, err := c.ChannelService.Publish(context.Background(), channelID, userID, requestBody.Text)
+	if err != nil {
+		writeChannelError(w, err, "Error publishing post")
+		return
+	}
+
+	response := struct {
+		Message string      `json:"message"`
+		Data    dto.Message `json:"data"`
+	}{
+		Message: "Post has been published",
+		Data:    dto.NewMessage(post),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+// RecordViews takes the ids of the posts a reader has seen, in batches, so a
+// client scrolling through a channel does not send a request per post.
+func (c *ChannelControllerImpl) RecordViews(w http.ResponseWriter, r *http.Request) {
+	channelID, ok := readConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	var requestBody struct {
+		MessageIDs []int `json:"message_ids"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if len(requestBody.MessageIDs) > maxViewBatchSize {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Too many posts in one batch"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	if err := c.ChannelService.RecordViews(context.Background(), channelID, userID, requestBody.MessageIDs); err != nil {
+		writeChannelError(w, err, "Error recording views")
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Views have been recorded",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func writeChannelError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrChannelNotFound):
+		httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrChannelPrivate), errors.Is(err, service.ErrNotSubscribed):
+		httputil.WriteResponse(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+	default:
+		writePolicyError(w, err, fallback)
+	}
+}
@@ -0,0 +1,285 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/messaging-go-service/internal/dto"
+	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/repository"
+	"github.com/messaging-go-service/middleware"
+	httputil "github.com/messaging-go-service/pkg/http"
+)
+
+const maxContactImportSize = 1000
+
+type ContactController interface {
+	GetContacts(w http.ResponseWriter, r *http.Request)
+	AddContact(w http.ResponseWriter, r *http.Request)
+	UpdateContact(w http.ResponseWriter, r *http.Request)
+	DeleteContact(w http.ResponseWriter, r *http.Request)
+	ImportContacts(w http.ResponseWriter, r *http.Request)
+}
+
+type ContactControllerImpl struct {
+	ContactRepository repository.ContactRepository
+	UserRepository    repository.UserRepository
+}
+
+func NewContactController(contactRepo repository.ContactRepository, userRepo repository.UserRepository) ContactController {
+	return &ContactControllerImpl{
+		ContactRepository: contactRepo,
+		UserRepository:    userRepo,
+	}
+}
+
+func (c *ContactControllerImpl) GetContacts(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	sort := r.URL.Query().Get("sort")
+	if sort != repository.ContactSortRecent {
+		sort = repository.ContactSortName
+	}
+	pagination := httputil.ReadPagination(r)
+
+	contacts, total, err := c.ContactRepository.GetContactsByUserID(context.Background(), userID, sort, pagination.Limit, pagination.Offset())
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error retrieving contacts"})
+		return
+	}
+	pagination.Total = total
+
+	response := struct {
+		Message    string              `json:"message"`
+		Data       []dto.Contact       `json:"data"`
+		Pagination httputil.Pagination `json:"pagination"`
+	}{
+		Message:    "Contacts have been retrieved",
+		Data:       dto.NewContacts(contacts),
+		Pagination: pagination,
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ContactControllerImpl) AddContact(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	var requestBody struct {
+		ContactUserID int    `json:"contact_user_id"`
+		SavedName     string `json:"saved_name"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if requestBody.ContactUserID == userID {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "You cannot add yourself as a contact"})
+		return
+	}
+
+	if _, err := c.UserRepository.GetUserByID(context.Background(), requestBody.ContactUserID); err != nil {
+		httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	newContact := model.Contact{
+		UserID:        userID,
+		ContactUserID: requestBody.ContactUserID,
+		SavedName:     strings.TrimSpace(requestBody.SavedName),
+	}
+
+	if err := c.ContactRepository.CreateContact(context.Background(), &newContact); err != nil {
+		if repository.IsDuplicateKey(err) {
+			httputil.WriteResponse(w, http.StatusConflict, map[string]string{"error": "User is already in your contacts"})
+			return
+		}
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error adding contact"})
+		return
+	}
+
+	contact, err := c.ContactRepository.GetContactByID(context.Background(), newContact.ID)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error retrieving contact"})
+		return
+	}
+
+	response := struct {
+		Message string      `json:"message"`
+		Data    dto.Contact `json:"data"`
+	}{
+		Message: "Contact has been added",
+		Data:    dto.NewContact(contact),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ContactControllerImpl) UpdateContact(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	contact, ok := c.getOwnContact(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var requestBody struct {
+		SavedName string `json:"saved_name"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	updatedContact := model.Contact{
+		SavedName: strings.TrimSpace(requestBody.SavedName),
+	}
+
+	if err := c.ContactRepository.UpdateContact(context.Background(), contact.ID, &updatedContact); err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error updating contact"})
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Contact has been updated",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ContactControllerImpl) DeleteContact(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	contact, ok := c.getOwnContact(w, r, userID)
+	if !ok {
+		return
+	}
+
+	if err := c.ContactRepository.DeleteContact(context.Background(), contact.ID); err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error deleting contact"})
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Contact has been deleted",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+// ImportContacts matches an uploaded address book against registered users.
+// Entries carry the SHA-256 of the normalized email (see model.HashEmail) so
+// the server never learns the addresses of people who are not registered.
+// Only users who let the importer find them by email are matched and saved
+// as contacts; by default that means users who saved the importer first.
+func (c *ContactControllerImpl) ImportContacts(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	var requestBody struct {
+		Contacts []struct {
+			EmailHash string `json:"email_hash"`
+			SavedName string `json:"saved_name"`
+		} `json:"contacts"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if len(requestBody.Contacts) > maxContactImportSize {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Too many contacts, the limit is " + strconv.Itoa(maxContactImportSize)})
+		return
+	}
+
+	savedNames := make(map[string]string, len(requestBody.Contacts))
+	hashes := make([]string, 0, len(requestBody.Contacts))
+	for _, entry := range requestBody.Contacts {
+		if strings.TrimSpace(entry.EmailHash) == "" {
+			continue
+		}
+		hash := model.KeyEmailHash(entry.EmailHash)
+		savedNames[hash] = strings.TrimSpace(entry.SavedName)
+		hashes = append(hashes, hash)
+	}
+
+	users, err := c.UserRepository.GetUsersByEmailHashes(context.Background(), userID, hashes)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error importing contacts"})
+		return
+	}
+
+	var newContacts []model.Contact
+	for _, user := range users {
+		if user.ID == userID {
+			continue
+		}
+		newContacts = append(newContacts, model.Contact{
+			UserID:        userID,
+			ContactUserID: user.ID,
+			SavedName:     savedNames[user.EmailHash],
+		})
+	}
+
+	if err := c.ContactRepository.CreateContacts(context.Background(), newContacts); err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error importing contacts"})
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Contacts have been imported",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ContactControllerImpl) getOwnContact(w http.ResponseWriter, r *http.Request, userID int) (*model.Contact, bool) {
+	vars := mux.Vars(r)
+	contactIDstr := vars["id"]
+
+	contactID, err := strconv.Atoi(contactIDstr)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid contact id"})
+		return nil, false
+	}
+
+	contact, err := c.ContactRepository.GetContactByID(context.Background(), contactID)
+	if err != nil || contact.UserID != userID {
+		httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": "Contact not found"})
+		return nil, false
+	}
+
+	return contact, true
+}
@@ -2,41 +2,98 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
+	"github.com/messaging-go-service/internal/dto"
 	"github.com/messaging-go-service/internal/model"
 	"github.com/messaging-go-service/internal/repository"
+	"github.com/messaging-go-service/internal/service"
+	"github.com/messaging-go-service/middleware"
 	httputil "github.com/messaging-go-service/pkg/http"
 )
 
+const (
+	maxTitleLength       = 128
+	maxDescriptionLength = 512
+	maxSlowModeSeconds   = 3600
+	maxMessageTTLSeconds = 365 * 24 * 3600
+	maxAvatarSize        = 5 << 20
+)
+
+// avatarExtensions maps the image types accepted as a conversation photo to
+// the extension they are stored with.
+var avatarExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
 type ConversationController interface {
 	AddConversation(w http.ResponseWriter, r *http.Request)
+	GetOrCreateDirectConversation(w http.ResponseWriter, r *http.Request)
 	DeleteConversation(w http.ResponseWriter, r *http.Request)
 	GetConversationsByUserID(w http.ResponseWriter, r *http.Request)
 	AddParticipant(w http.ResponseWriter, r *http.Request)
 	AddMessage(w http.ResponseWriter, r *http.Request)
 	GetConversationDetail(w http.ResponseWriter, r *http.Request)
 	RetrieveMessages(w http.ResponseWriter, r *http.Request)
+	GetRequestConversations(w http.ResponseWriter, r *http.Request)
+	AcceptRequest(w http.ResponseWriter, r *http.Request)
+	DeclineRequest(w http.ResponseWriter, r *http.Request)
+	ReportRequest(w http.ResponseWriter, r *http.Request)
+	RemoveParticipant(w http.ResponseWriter, r *http.Request)
+	LeaveConversation(w http.ResponseWriter, r *http.Request)
+	ChangeParticipantRole(w http.ResponseWriter, r *http.Request)
+	UpdateSettings(w http.ResponseWriter, r *http.Request)
+	ReorderPins(w http.ResponseWriter, r *http.Request)
+	TransferOwnership(w http.ResponseWriter, r *http.Request)
+	UpdateConversation(w http.ResponseWriter, r *http.Request)
+	UpdateAvatar(w http.ResponseWriter, r *http.Request)
+	RemoveAvatar(w http.ResponseWriter, r *http.Request)
 }
 
 type ConversationControllerImpl struct {
 	ConversationRepository repository.ConversationRepository
 	UserRepository         repository.UserRepository
+	BlockRepository        repository.BlockRepository
+	ReportRepository       repository.ReportRepository
+	DisplayNameResolver    service.DisplayNameResolver
+	Policy                 service.Policy
+	ConversationService    service.ConversationService
+	MessageService         service.MessageService
 }
 
-func NewConversationController(conversationRepo repository.ConversationRepository, userRepo repository.UserRepository) ConversationController {
+func NewConversationController(conversationRepo repository.ConversationRepository, userRepo repository.UserRepository, blockRepo repository.BlockRepository, reportRepo repository.ReportRepository, displayNameResolver service.DisplayNameResolver, policy service.Policy, conversationService service.ConversationService, messageService service.MessageService) ConversationController {
 	return &ConversationControllerImpl{
 		ConversationRepository: conversationRepo,
 		UserRepository:         userRepo,
+		BlockRepository:        blockRepo,
+		ReportRepository:       reportRepo,
+		DisplayNameResolver:    displayNameResolver,
+		Policy:                 policy,
+		ConversationService:    conversationService,
+		MessageService:         messageService,
 	}
 }
 
 func (c *ConversationControllerImpl) AddConversation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
 	var requestBody struct {
-		Title  string `json:"title"`
-		UserID int    `json:"user_id"`
+		Title string `json:"title"`
+		Kind  string `json:"kind"`
 	}
 
 	if err := httputil.ReadRequest(r, &requestBody); err != nil {
@@ -44,9 +101,31 @@ func (c *ConversationControllerImpl) AddConversation(w http.ResponseWriter, r *h
 		return
 	}
 
+	switch requestBody.Kind {
+	case "", model.ConversationKindGroup:
+	case model.ConversationKindDirect:
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Direct conversations are created with /api/conversation/direct"})
+		return
+	case model.ConversationKindChannel:
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Channels are created with /api/channel"})
+		return
+	default:
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid conversation kind"})
+		return
+	}
+
 	newConversation := model.Conversation{
-		UserID: requestBody.UserID,
+		UserID: userID,
+		Kind:   model.ConversationKindGroup,
 		Title:  requestBody.Title,
+		Participants: []model.Participant{
+			{
+				UserID:        userID,
+				Role:          model.RoleOwner,
+				RequestStatus: model.RequestStatusAccepted,
+				InvitedByID:   userID,
+			},
+		},
 	}
 
 	if err := c.ConversationRepository.CreateConversation(context.Background(), &newConversation); err != nil {
@@ -55,11 +134,65 @@ func (c *ConversationControllerImpl) AddConversation(w http.ResponseWriter, r *h
 	}
 
 	response := struct {
-		Message string             `json:"message"`
-		Data    model.Conversation `json:"data"`
+		Message string           `json:"message"`
+		Data    dto.Conversation `json:"data"`
 	}{
 		Message: "Conversation has been created",
-		Data:    newConversation,
+		Data:    dto.NewConversation(&newConversation),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ConversationControllerImpl) GetOrCreateDirectConversation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	var requestBody struct {
+		UserID int `json:"user_id"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	if requestBody.UserID == userID {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "You cannot start a chat with yourself"})
+		return
+	}
+
+	if _, err := c.UserRepository.GetUserByID(context.Background(), requestBody.UserID); err != nil {
+		httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	conversation, created, err := c.ConversationService.GetOrCreateDirect(context.Background(), userID, requestBody.UserID)
+	if err != nil {
+		writePolicyError(w, err, "Error creating conversation")
+		return
+	}
+
+	conversations := []model.Conversation{*conversation}
+	if err := c.applyDisplayNames(userID, conversations); err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error retrieving conversation detail"})
+		return
+	}
+
+	message := "Conversation has been retrieved"
+	if created {
+		message = "Conversation has been created"
+	}
+
+	response := struct {
+		Message string           `json:"message"`
+		Data    dto.Conversation `json:"data"`
+	}{
+		Message: message,
+		Data:    dto.NewConversation(&conversations[0]),
 	}
 
 	httputil.WriteResponse(w, http.StatusOK, response)
@@ -69,6 +202,7 @@ func (c *ConversationControllerImpl) AddMessage(w http.ResponseWriter, r *http.R
 	var requestBody struct {
 		ParticipantID int    `json:"participant_id"`
 		Text          string `json:"text"`
+		TTLSeconds    int    `json:"ttl_seconds"`
 	}
 
 	if err := httputil.ReadRequest(r, &requestBody); err != nil {
@@ -76,22 +210,46 @@ func (c *ConversationControllerImpl) AddMessage(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	if requestBody.TTLSeconds < 0 || requestBody.TTLSeconds > maxMessageTTLSeconds {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Message lifetime must be between 0 seconds and one year"})
+		return
+	}
+
+	participant, err := c.ConversationRepository.GetParticipantByID(context.Background(), requestBody.ParticipantID)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": "Participant not found"})
+		return
+	}
+
+	if userID, _ := middleware.GetUserID(r); participant.UserID != userID {
+		httputil.WriteResponse(w, http.StatusForbidden, map[string]string{"error": "You are not a participant of this conversation"})
+		return
+	}
+
+	if err := c.Policy.CanSendMessage(context.Background(), participant.UserID, participant.ConversationID, requestBody.Text); err != nil {
+		writePolicyError(w, err, "Error adding message")
+		return
+	}
+
 	newMessage := model.Message{
-		ParticipantID: requestBody.ParticipantID,
-		Text:          requestBody.Text,
+		ConversationID: participant.ConversationID,
+		ParticipantID:  participant.ID,
+		Kind:           model.MessageKindText,
+		Text:           requestBody.Text,
+		TTLSeconds:     requestBody.TTLSeconds,
 	}
 
-	if err := c.ConversationRepository.AddMessage(context.Background(), &newMessage); err != nil {
-		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error adding message"})
+	if err := c.MessageService.Send(context.Background(), &newMessage, participant.UserID); err != nil {
+		writePolicyError(w, err, "Error adding message")
 		return
 	}
 
 	response := struct {
-		Message string        `json:"message"`
-		Data    model.Message `json:"data"`
+		Message string      `json:"message"`
+		Data    dto.Message `json:"data"`
 	}{
 		Message: "Message has been created",
-		Data:    newMessage,
+		Data:    dto.NewMessage(&newMessage),
 	}
 
 	httputil.WriteResponse(w, http.StatusOK, response)
@@ -107,18 +265,34 @@ func (c *ConversationControllerImpl) GetConversationsByUserID(w http.ResponseWri
 		return
 	}
 
-	conversations, err := c.ConversationRepository.GetConversationsByUserID(context.Background(), userID)
+	if viewerID, _ := middleware.GetUserID(r); viewerID != userID {
+		httputil.WriteResponse(w, http.StatusForbidden, map[string]string{"error": "You can only list your own conversations"})
+		return
+	}
+
+	pagination := httputil.ReadPagination(r)
+	archived := r.URL.Query().Get("archived") == "true"
+
+	conversations, total, err := c.ConversationRepository.GetConversationsByUserID(context.Background(), userID, archived, pagination.Limit, pagination.Offset())
 	if err != nil {
 		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error retrieving conversatiosn"})
 		return
 	}
+	pagination.Total = total
+
+	if err := c.applyDisplayNames(userID, conversations); err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error retrieving conversations"})
+		return
+	}
 
 	response := struct {
-		Message string               `json:"message"`
-		Data    []model.Conversation `json:"data"`
+		Message    string              `json:"message"`
+		Data       []dto.Conversation  `json:"data"`
+		Pagination httputil.Pagination `json:"pagination"`
 	}{
-		Message: "Conversations have been retrieved",
-		Data:    conversations,
+		Message:    "Conversations have been retrieved",
+		Data:       dto.NewConversations(conversations),
+		Pagination: pagination,
 	}
 
 	httputil.WriteResponse(w, http.StatusOK, response)
@@ -140,12 +314,19 @@ func (c *ConversationControllerImpl) GetConversationDetail(w http.ResponseWriter
 		return
 	}
 
+	viewerID, _ := middleware.GetUserID(r)
+	conversations := []model.Conversation{*conversation}
+	if err := c.applyDisplayNames(viewerID, conversations); err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error retrieving conversation detail"})
+		return
+	}
+
 	response := struct {
-		Message string             `json:"message"`
-		Data    model.Conversation `json:"data"`
+		Message string           `json:"message"`
+		Data    dto.Conversation `json:"data"`
 	}{
 		Message: "Conversation detail has been retrieved",
-		Data:    *conversation,
+		Data:    dto.NewConversation(&conversations[0]),
 	}
 
 	httputil.WriteResponse(w, http.StatusOK, response)
@@ -162,13 +343,9 @@ func (c *ConversationControllerImpl) AddParticipant(w http.ResponseWriter, r *ht
 		return
 	}
 
-	newParticipant := model.Participant{
-		UserID:         requestBody.UserID,
-		ConversationID: requestBody.ConversationID,
-	}
-
-	if err := c.ConversationRepository.AddParticipant(context.Background(), &newParticipant); err != nil {
-		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error adding participant"})
+	actorID, _ := middleware.GetUserID(r)
+	if _, err := c.ConversationService.AddParticipant(context.Background(), requestBody.ConversationID, actorID, requestBody.UserID); err != nil {
+		writeConversationError(w, err, "Error adding participant")
 		return
 	}
 
@@ -191,6 +368,12 @@ func (c *ConversationControllerImpl) DeleteConversation(w http.ResponseWriter, r
 		return
 	}
 
+	userID, _ := middleware.GetUserID(r)
+	if _, _, err := c.ConversationService.Authorize(context.Background(), conversationID, userID, service.ActionDeleteConversation); err != nil {
+		writeConversationError(w, err, "Error deleting conversation")
+		return
+	}
+
 	if err := c.ConversationRepository.DeleteConversation(context.Background(), conversationID); err != nil {
 		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error deleting conversation"})
 		return
@@ -215,18 +398,579 @@ func (c *ConversationControllerImpl) RetrieveMessages(w http.ResponseWriter, r *
 		return
 	}
 
-	messages, err := c.ConversationRepository.GetMessagesByConversationID(context.Background(), conversationID)
+	viewerID, _ := middleware.GetUserID(r)
+	participant, err := c.ConversationRepository.GetParticipant(context.Background(), conversationID, viewerID)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusForbidden, map[string]string{"error": "You are not a participant of this conversation"})
+		return
+	}
+
+	// Former members only see the history up to when they left, and members
+	// who rejoined do not see what was sent while they were away.
+	messages, err := c.ConversationRepository.GetMessagesByConversationID(context.Background(), participant)
 	if err != nil {
 		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error deleting conversation"})
 		return
 	}
 
+	if err := c.applySenderNames(viewerID, conversationID, messages); err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error retrieving messages"})
+		return
+	}
+
 	response := struct {
-		Message string          `json:"message"`
-		Data    []model.Message `json:"data"`
+		Message string        `json:"message"`
+		Data    []dto.Message `json:"data"`
 	}{
 		Message: "Conversation has been deleted",
-		Data:    messages,
+		Data:    dto.NewMessages(messages),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ConversationControllerImpl) applyDisplayNames(viewerID int, conversations []model.Conversation) error {
+	var userIDs []int
+	for _, conversation := range conversations {
+		for _, participant := range conversation.Participants {
+			userIDs = append(userIDs, participant.UserID)
+		}
+	}
+
+	names, err := c.DisplayNameResolver.Resolve(context.Background(), viewerID, userIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range conversations {
+		conversation := &conversations[i]
+		for j := range conversation.Participants {
+			participant := &conversation.Participants[j]
+			participant.DisplayName = names[participant.UserID].Name
+
+			if conversation.LastMessage != nil && conversation.LastMessage.ParticipantID == participant.ID {
+				conversation.LastMessage.SenderName = participant.DisplayName
+			}
+
+			// A direct conversation is presented as the other participant.
+			if conversation.Kind == model.ConversationKindDirect && participant.UserID != viewerID {
+				conversation.Title = names[participant.UserID].Name
+				conversation.Avatar = names[participant.UserID].Avatar
+			}
+		}
+	}
+	return nil
+}
+
+func (c *ConversationControllerImpl) applySenderNames(viewerID int, conversationID int, messages []model.Message) error {
+	participants, err := c.ConversationRepository.GetParticipantsByConversationID(context.Background(), conversationID)
+	if err != nil {
+		return err
+	}
+
+	senders := make(map[int]int, len(participants))
+	userIDs := make([]int, 0, len(participants))
+	for _, participant := range participants {
+		senders[participant.ID] = participant.UserID
+		userIDs = append(userIDs, participant.UserID)
+	}
+
+	names, err := c.DisplayNameResolver.Resolve(context.Background(), viewerID, userIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range messages {
+		messages[i].SenderName = names[senders[messages[i].ParticipantID]].Name
+	}
+	return nil
+}
+
+func (c *ConversationControllerImpl) GetRequestConversations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	conversations, err := c.ConversationRepository.GetRequestConversationsByUserID(context.Background(), userID)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error retrieving message requests"})
+		return
+	}
+
+	if err := c.applyDisplayNames(userID, conversations); err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error retrieving message requests"})
+		return
+	}
+
+	response := struct {
+		Message string             `json:"message"`
+		Data    []dto.Conversation `json:"data"`
+	}{
+		Message: "Message requests have been retrieved",
+		Data:    dto.NewConversations(conversations),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ConversationControllerImpl) AcceptRequest(w http.ResponseWriter, r *http.Request) {
+	participant, ok := c.getRequestParticipant(w, r, model.RequestStatusPending, model.RequestStatusDeclined)
+	if !ok {
+		return
+	}
+
+	if err := c.ConversationRepository.UpdateParticipantRequestStatus(context.Background(), participant.ID, model.RequestStatusAccepted); err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error accepting message request"})
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Message request has been accepted",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ConversationControllerImpl) DeclineRequest(w http.ResponseWriter, r *http.Request) {
+	participant, ok := c.getRequestParticipant(w, r, model.RequestStatusPending)
+	if !ok {
+		return
+	}
+
+	if err := c.ConversationRepository.UpdateParticipantRequestStatus(context.Background(), participant.ID, model.RequestStatusDeclined); err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error declining message request"})
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Message request has been declined",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ConversationControllerImpl) ReportRequest(w http.ResponseWriter, r *http.Request) {
+	participant, ok := c.getRequestParticipant(w, r, model.RequestStatusPending, model.RequestStatusDeclined)
+	if !ok {
+		return
+	}
+
+	var requestBody struct {
+		Reason string `json:"reason"`
+		Block  bool   `json:"block"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	newReport := model.Report{
+		UserID:         participant.UserID,
+		ReportedUserID: participant.InvitedByID,
+		ConversationID: participant.ConversationID,
+		Reason:         requestBody.Reason,
+	}
+
+	if err := c.ReportRepository.CreateReport(context.Background(), &newReport); err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error reporting message request"})
+		return
+	}
+
+	if err := c.ConversationRepository.UpdateParticipantRequestStatus(context.Background(), participant.ID, model.RequestStatusDeclined); err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error declining message request"})
+		return
+	}
+
+	if requestBody.Block && participant.InvitedByID != 0 {
+		newBlock := model.Block{
+			UserID:        participant.UserID,
+			BlockedUserID: participant.InvitedByID,
+		}
+		if err := c.BlockRepository.CreateBlock(context.Background(), &newBlock); err != nil {
+			httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error blocking user"})
+			return
+		}
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Message request has been reported",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+// getRequestParticipant loads the authenticated user's membership of the
+// conversation in the path and checks its request is in one of the given states.
+func (c *ConversationControllerImpl) getRequestParticipant(w http.ResponseWriter, r *http.Request, statuses ...string) (*model.Participant, bool) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return nil, false
+	}
+
+	vars := mux.Vars(r)
+	conversationIDstr := vars["id"]
+
+	conversationID, err := strconv.Atoi(conversationIDstr)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid conversation id"})
+		return nil, false
+	}
+
+	participant, err := c.ConversationRepository.GetParticipant(context.Background(), conversationID, userID)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": "Message request not found"})
+		return nil, false
+	}
+
+	for _, status := range statuses {
+		if participant.RequestStatus == status {
+			return participant, true
+		}
+	}
+
+	httputil.WriteResponse(w, http.StatusConflict, map[string]string{"error": "Message request is already " + participant.RequestStatus})
+	return nil, false
+}
+
+func (c *ConversationControllerImpl) ChangeParticipantRole(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid conversation id"})
+		return
+	}
+
+	targetUserID, err := strconv.Atoi(vars["user_id"])
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid user id"})
+		return
+	}
+
+	var requestBody struct {
+		Role string `json:"role"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if !service.IsValidRole(requestBody.Role) || requestBody.Role == model.RoleOwner {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid role"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	if err := c.ConversationService.ChangeRole(context.Background(), conversationID, userID, targetUserID, requestBody.Role); err != nil {
+		writeConversationError(w, err, "Error changing role")
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Role has been changed",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ConversationControllerImpl) TransferOwnership(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid conversation id"})
+		return
+	}
+
+	var requestBody struct {
+		UserID int `json:"user_id"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	if err := c.ConversationService.TransferOwnership(context.Background(), conversationID, userID, requestBody.UserID); err != nil {
+		writeConversationError(w, err, "Error transferring ownership")
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Ownership has been transferred",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ConversationControllerImpl) RemoveParticipant(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	conversationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid conversation id"})
+		return
+	}
+
+	targetUserID, err := strconv.Atoi(vars["user_id"])
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid user id"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	if err := c.ConversationService.RemoveParticipant(context.Background(), conversationID, userID, targetUserID); err != nil {
+		writeConversationError(w, err, "Error removing participant")
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Participant has been removed",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ConversationControllerImpl) LeaveConversation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	conversationIDstr := vars["id"]
+
+	conversationID, err := strconv.Atoi(conversationIDstr)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid conversation id"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	if err := c.ConversationService.Leave(context.Background(), conversationID, userID); err != nil {
+		writeConversationError(w, err, "Error leaving conversation")
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "You have left the conversation",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+// UpdateSettings changes the caller's own preferences for a conversation.
+// Fields left out of the body are not changed.
+func (c *ConversationControllerImpl) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	conversationIDstr := vars["id"]
+
+	conversationID, err := strconv.Atoi(conversationIDstr)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid conversation id"})
+		return
+	}
+
+	var requestBody service.ParticipantSettings
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if requestBody.MutedUntil != nil && !requestBody.MutedUntil.After(time.Now()) {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Mute end must be in the future"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	if err := c.ConversationService.UpdateSettings(context.Background(), conversationID, userID, requestBody); err != nil {
+		if errors.Is(err, service.ErrTooManyPins) {
+			httputil.WriteResponse(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		writeConversationError(w, err, "Error updating settings")
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Settings have been updated",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ConversationControllerImpl) ReorderPins(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	var requestBody struct {
+		ConversationIDs []int `json:"conversation_ids"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if err := c.ConversationRepository.ReorderPins(context.Background(), userID, requestBody.ConversationIDs); err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error reordering pinned conversations"})
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Pinned conversations have been reordered",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ConversationControllerImpl) UpdateConversation(w http.ResponseWriter, r *http.Request) {
+	conversationID, ok := readConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	var requestBody service.ConversationInfo
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if requestBody.Title != nil {
+		title := strings.TrimSpace(*requestBody.Title)
+		if title == "" || utf8.RuneCountInString(title) > maxTitleLength {
+			httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Title must be between 1 and 128 characters"})
+			return
+		}
+		requestBody.Title = &title
+	}
+	if requestBody.Description != nil {
+		description := strings.TrimSpace(*requestBody.Description)
+		if utf8.RuneCountInString(description) > maxDescriptionLength {
+			httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Description must be at most 512 characters"})
+			return
+		}
+		requestBody.Description = &description
+	}
+	if requestBody.SlowModeSeconds != nil && (*requestBody.SlowModeSeconds < 0 || *requestBody.SlowModeSeconds > maxSlowModeSeconds) {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Slow mode must be between 0 and 3600 seconds"})
+		return
+	}
+	if requestBody.MessageTTLSeconds != nil && (*requestBody.MessageTTLSeconds < 0 || *requestBody.MessageTTLSeconds > maxMessageTTLSeconds) {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Message lifetime must be between 0 seconds and one year"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	conversation, err := c.ConversationService.UpdateInfo(context.Background(), conversationID, userID, requestBody)
+	if err != nil {
+		writeConversationError(w, err, "Error updating conversation")
+		return
+	}
+
+	response := struct {
+		Message string           `json:"message"`
+		Data    dto.Conversation `json:"data"`
+	}{
+		Message: "Conversation has been updated",
+		Data:    dto.NewConversation(conversation),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ConversationControllerImpl) UpdateAvatar(w http.ResponseWriter, r *http.Request) {
+	conversationID, ok := readConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarSize+1<<20)
+	if err := r.ParseMultipartForm(maxAvatarSize); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Image file is required"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Error reading image"})
+		return
+	}
+
+	ext, ok := avatarExtensions[http.DetectContentType(data)]
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnsupportedMediaType, map[string]string{"error": "Photos must be JPEG, PNG, GIF or WebP images"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	conversation, err := c.ConversationService.UpdateAvatar(context.Background(), conversationID, userID, ext, data)
+	if err != nil {
+		writeConversationError(w, err, "Error updating photo")
+		return
+	}
+
+	response := struct {
+		Message string           `json:"message"`
+		Data    dto.Conversation `json:"data"`
+	}{
+		Message: "Photo has been updated",
+		Data:    dto.NewConversation(conversation),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ConversationControllerImpl) RemoveAvatar(w http.ResponseWriter, r *http.Request) {
+	conversationID, ok := readConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	conversation, err := c.ConversationService.UpdateAvatar(context.Background(), conversationID, userID, "", nil)
+	if err != nil {
+		writeConversationError(w, err, "Error removing photo")
+		return
+	}
+
+	response := struct {
+		Message string           `json:"message"`
+		Data    dto.Conversation `json:"data"`
+	}{
+		Message: "Photo has been removed",
+		Data:    dto.NewConversation(conversation),
 	}
 
 	httputil.WriteResponse(w, http.StatusOK, response)
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/messaging-go-service/internal/service"
+	httputil "github.com/messaging-go-service/pkg/http"
+	"gorm.io/gorm"
+)
+
+// writePolicyError answers a request rejected by service.Policy, hiding
+// unexpected errors behind the given fallback message.
+func writePolicyError(w http.ResponseWriter, err error, fallback string) {
+	var rateLimited *service.RateLimitError
+	if errors.As(err, &rateLimited) {
+		w.Header().Set("Retry-After", strconv.Itoa(rateLimited.RetryAfterSeconds()))
+		httputil.WriteResponse(w, http.StatusTooManyRequests, map[string]string{"error": err.Error()})
+		return
+	}
+	if service.IsPolicyViolation(err) {
+		httputil.WriteResponse(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+	httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": fallback})
+}
+
+// writeConversationError is writePolicyError for lookups that start from a
+// conversation id, answering 404 when the conversation does not exist.
+func writeConversationError(w http.ResponseWriter, err error, fallback string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": "Conversation not found"})
+		return
+	}
+	if errors.Is(err, service.ErrParticipantNotFound) {
+		httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": "Participant not found"})
+		return
+	}
+	if errors.Is(err, service.ErrUserNotFound) {
+		httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+	if errors.Is(err, service.ErrAlreadyParticipant) {
+		httputil.WriteResponse(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+	writePolicyError(w, err, fallback)
+}
+
+// writeUserError answers a request to register or rename a user.
+func writeUserError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+	case errors.Is(err, service.ErrInvalidUsername), errors.Is(err, service.ErrInvalidEmail),
+		errors.Is(err, service.ErrPasswordRequired), errors.Is(err, service.ErrUsernameUnchanged):
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrUsernameTaken), errors.Is(err, service.ErrUsernameReserved), errors.Is(err, service.ErrEmailTaken):
+		httputil.WriteResponse(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrUsernameChangeTooSoon):
+		httputil.WriteResponse(w, http.StatusTooManyRequests, map[string]string{"error": err.Error()})
+	default:
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": fallback})
+	}
+}
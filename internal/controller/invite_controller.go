@@ -0,0 +1,269 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/messaging-go-service/internal/dto"
+	"github.com/messaging-go-service/internal/service"
+	"github.com/messaging-go-service/middleware"
+	httputil "github.com/messaging-go-service/pkg/http"
+)
+
+type InviteController interface {
+	CreateInviteLink(w http.ResponseWriter, r *http.Request)
+	GetInviteLinks(w http.ResponseWriter, r *http.Request)
+	RevokeInviteLink(w http.ResponseWriter, r *http.Request)
+	PreviewInvite(w http.ResponseWriter, r *http.Request)
+	JoinByInvite(w http.ResponseWriter, r *http.Request)
+	GetJoinRequests(w http.ResponseWriter, r *http.Request)
+	ApproveJoinRequest(w http.ResponseWriter, r *http.Request)
+	DeclineJoinRequest(w http.ResponseWriter, r *http.Request)
+}
+
+type InviteControllerImpl struct {
+	InviteService service.InviteService
+}
+
+func NewInviteController(inviteService service.InviteService) InviteController {
+	return &InviteControllerImpl{
+		InviteService: inviteService,
+	}
+}
+
+func (c *InviteControllerImpl) CreateInviteLink(w http.ResponseWriter, r *http.Request) {
+	conversationID, ok := readConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	var requestBody struct {
+		ExpiresInSeconds int  `json:"expires_in_seconds"`
+		MaxUses          int  `json:"max_uses"`
+		RequiresApproval bool `json:"requires_approval"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if requestBody.ExpiresInSeconds < 0 || requestBody.MaxUses < 0 {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Expiry and max uses cannot be negative"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	expiresIn := time.Duration(requestBody.ExpiresInSeconds) * time.Second
+	link, err := c.InviteService.CreateLink(context.Background(), conversationID, userID, expiresIn, requestBody.MaxUses, requestBody.RequiresApproval)
+	if err != nil {
+		writeInviteError(w, err, "Error creating invite link")
+		return
+	}
+
+	response := struct {
+		Message string         `json:"message"`
+		Data    dto.InviteLink `json:"data"`
+	}{
+		Message: "Invite link has been created",
+		Data:    dto.NewInviteLink(link),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *InviteControllerImpl) GetInviteLinks(w http.ResponseWriter, r *http.Request) {
+	conversationID, ok := readConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	links, err := c.InviteService.GetLinks(context.Background(), conversationID, userID)
+	if err != nil {
+		writeInviteError(w, err, "Error retrieving invite links")
+		return
+	}
+
+	response := struct {
+		Message string           `json:"message"`
+		Data    []dto.InviteLink `json:"data"`
+	}{
+		Message: "Invite links have been retrieved",
+		Data:    dto.NewInviteLinks(links),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *InviteControllerImpl) RevokeInviteLink(w http.ResponseWriter, r *http.Request) {
+	conversationID, ok := readConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	linkID, err := strconv.Atoi(mux.Vars(r)["invite_id"])
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid invite id"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	if err := c.InviteService.RevokeLink(context.Background(), conversationID, userID, linkID); err != nil {
+		writeInviteError(w, err, "Error revoking invite link")
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Invite link has been revoked",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *InviteControllerImpl) PreviewInvite(w http.ResponseWriter, r *http.Request) {
+	preview, err := c.InviteService.Preview(context.Background(), mux.Vars(r)["token"])
+	if err != nil {
+		writeInviteError(w, err, "Error retrieving invite")
+		return
+	}
+
+	response := struct {
+		Message string                `json:"message"`
+		Data    service.InvitePreview `json:"data"`
+	}{
+		Message: "Invite has been retrieved",
+		Data:    *preview,
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *InviteControllerImpl) JoinByInvite(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	participant, request, err := c.InviteService.Join(context.Background(), mux.Vars(r)["token"], userID)
+	if err != nil {
+		writeInviteError(w, err, "Error joining conversation")
+		return
+	}
+
+	if request != nil {
+		response := struct {
+			Message string          `json:"message"`
+			Data    dto.JoinRequest `json:"data"`
+		}{
+			Message: "Your request to join has been sent",
+			Data:    dto.NewJoinRequest(request),
+		}
+
+		httputil.WriteResponse(w, http.StatusAccepted, response)
+		return
+	}
+
+	response := struct {
+		Message string          `json:"message"`
+		Data    dto.Participant `json:"data"`
+	}{
+		Message: "You have joined the conversation",
+		Data:    dto.NewParticipant(participant),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *InviteControllerImpl) GetJoinRequests(w http.ResponseWriter, r *http.Request) {
+	conversationID, ok := readConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	requests, err := c.InviteService.GetJoinRequests(context.Background(), conversationID, userID)
+	if err != nil {
+		writeInviteError(w, err, "Error retrieving join requests")
+		return
+	}
+
+	response := struct {
+		Message string            `json:"message"`
+		Data    []dto.JoinRequest `json:"data"`
+	}{
+		Message: "Join requests have been retrieved",
+		Data:    dto.NewJoinRequests(requests),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *InviteControllerImpl) ApproveJoinRequest(w http.ResponseWriter, r *http.Request) {
+	c.reviewJoinRequest(w, r, true)
+}
+
+func (c *InviteControllerImpl) DeclineJoinRequest(w http.ResponseWriter, r *http.Request) {
+	c.reviewJoinRequest(w, r, false)
+}
+
+func (c *InviteControllerImpl) reviewJoinRequest(w http.ResponseWriter, r *http.Request, approve bool) {
+	conversationID, ok := readConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	requestID, err := strconv.Atoi(mux.Vars(r)["request_id"])
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid join request id"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	if err := c.InviteService.ReviewJoinRequest(context.Background(), conversationID, userID, requestID, approve); err != nil {
+		writeInviteError(w, err, "Error reviewing join request")
+		return
+	}
+
+	message := "Join request has been declined"
+	if approve {
+		message = "Join request has been approved"
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: message,
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func readConversationID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	conversationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid conversation id"})
+		return 0, false
+	}
+	return conversationID, true
+}
+
+func writeInviteError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInviteNotFound), errors.Is(err, service.ErrJoinRequestNotFound):
+		httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrInviteInvalid):
+		httputil.WriteResponse(w, http.StatusGone, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrJoinRequestDeclined), errors.Is(err, service.ErrRemovedByAdmin):
+		httputil.WriteResponse(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+	default:
+		writeConversationError(w, err, fallback)
+	}
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+
+	"github.com/gorilla/mux"
+	"github.com/messaging-go-service/internal/service"
+	"github.com/messaging-go-service/middleware"
+	httputil "github.com/messaging-go-service/pkg/http"
+)
+
+type MediaController interface {
+	GetMedia(w http.ResponseWriter, r *http.Request)
+}
+
+type MediaControllerImpl struct {
+	MediaService service.MediaService
+}
+
+func NewMediaController(mediaService service.MediaService) MediaController {
+	return &MediaControllerImpl{
+		MediaService: mediaService,
+	}
+}
+
+func (c *MediaControllerImpl) GetMedia(w http.ResponseWriter, r *http.Request) {
+	name := filepath.Base(mux.Vars(r)["name"])
+
+	userID, _ := middleware.GetUserID(r)
+	if err := c.MediaService.Authorize(context.Background(), service.MediaURLPrefix+name, userID); err != nil {
+		switch {
+		case errors.Is(err, service.ErrMediaNotFound):
+			httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": "Media not found"})
+		case errors.Is(err, service.ErrNotParticipant), errors.Is(err, service.ErrNotSubscribed):
+			httputil.WriteResponse(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		default:
+			httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error loading media"})
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private")
+	http.ServeFile(w, r, filepath.Join(service.MediaDir(), name))
+}
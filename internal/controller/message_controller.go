@@ -0,0 +1,502 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/messaging-go-service/internal/dto"
+	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/repository"
+	"github.com/messaging-go-service/internal/service"
+	"github.com/messaging-go-service/middleware"
+	"github.com/messaging-go-service/pkg/audio"
+	httputil "github.com/messaging-go-service/pkg/http"
+	"github.com/messaging-go-service/pkg/vcard"
+)
+
+const maxVoiceNoteSize = 16 << 20
+
+type MessageController interface {
+	AddVoiceMessage(w http.ResponseWriter, r *http.Request)
+	MarkPlayed(w http.ResponseWriter, r *http.Request)
+	GetPlays(w http.ResponseWriter, r *http.Request)
+	AddContactMessage(w http.ResponseWriter, r *http.Request)
+	ExportContact(w http.ResponseWriter, r *http.Request)
+	StartChatFromContact(w http.ResponseWriter, r *http.Request)
+	DeleteMessage(w http.ResponseWriter, r *http.Request)
+}
+
+type MessageControllerImpl struct {
+	MessageRepository      repository.MessageRepository
+	ConversationRepository repository.ConversationRepository
+	UserRepository         repository.UserRepository
+	MediaStorage           service.MediaStorage
+	Policy                 service.Policy
+	ConversationService    service.ConversationService
+	MessageService         service.MessageService
+}
+
+func NewMessageController(messageRepo repository.MessageRepository, conversationRepo repository.ConversationRepository, userRepo repository.UserRepository, mediaStorage service.MediaStorage, policy service.Policy, conversationService service.ConversationService, messageService service.MessageService) MessageController {
+	return &MessageControllerImpl{
+		MessageRepository:      messageRepo,
+		ConversationRepository: conversationRepo,
+		UserRepository:         userRepo,
+		MediaStorage:           mediaStorage,
+		Policy:                 policy,
+		ConversationService:    conversationService,
+		MessageService:         messageService,
+	}
+}
+
+func (c *MessageControllerImpl) AddVoiceMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxVoiceNoteSize+1<<20)
+	if err := r.ParseMultipartForm(maxVoiceNoteSize); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	participantID, err := strconv.Atoi(r.FormValue("participant_id"))
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid participant id"})
+		return
+	}
+
+	participant, err := c.ConversationRepository.GetParticipantByID(context.Background(), participantID)
+	if err != nil || participant.UserID != userID {
+		httputil.WriteResponse(w, http.StatusForbidden, map[string]string{"error": "You are not a participant of this conversation"})
+		return
+	}
+
+	if err := c.Policy.CanSendMessage(context.Background(), userID, participant.ConversationID, ""); err != nil {
+		writePolicyError(w, err, "Error adding message")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Voice note file is required"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Error reading voice note"})
+		return
+	}
+
+	info, err := audio.Probe(data)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusUnsupportedMediaType, map[string]string{"error": "Voice notes must be Opus/OGG or WAV audio"})
+		return
+	}
+
+	ext := ".ogg"
+	if info.Format == audio.FormatWAV {
+		ext = ".wav"
+	}
+
+	url, err := c.MediaStorage.Save(ext, data)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error storing voice note"})
+		return
+	}
+
+	newMessage := model.Message{
+		ConversationID: participant.ConversationID,
+		ParticipantID:  participant.ID,
+		Kind:           model.MessageKindVoice,
+		Media: &model.Media{
+			UserID:     userID,
+			Url:        url,
+			FileName:   header.Filename,
+			MimeType:   info.MimeType,
+			Size:       int64(len(data)),
+			DurationMs: info.Duration.Milliseconds(),
+			Waveform:   info.Waveform,
+		},
+	}
+
+	if err := c.MessageService.Send(context.Background(), &newMessage, userID); err != nil {
+		c.MediaStorage.Delete(url)
+		writePolicyError(w, err, "Error adding message")
+		return
+	}
+
+	response := struct {
+		Message string      `json:"message"`
+		Data    dto.Message `json:"data"`
+	}{
+		Message: "Voice message has been created",
+		Data:    dto.NewMessage(&newMessage),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *MessageControllerImpl) MarkPlayed(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	message, ok := c.getVisibleMessage(w, r, userID, model.MessageKindVoice)
+	if !ok {
+		return
+	}
+
+	participant, err := c.ConversationRepository.GetParticipantByID(context.Background(), message.ParticipantID)
+	if err == nil && participant.UserID == userID {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Senders cannot mark their own voice message as played"})
+		return
+	}
+
+	play := model.MessagePlay{
+		MessageID: message.ID,
+		UserID:    userID,
+		PlayedAt:  time.Now(),
+	}
+
+	if err := c.MessageRepository.MarkPlayed(context.Background(), &play); err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error marking message as played"})
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Voice message has been marked as played",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *MessageControllerImpl) GetPlays(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	message, ok := c.getVisibleMessage(w, r, userID, model.MessageKindVoice)
+	if !ok {
+		return
+	}
+
+	plays, err := c.MessageRepository.GetMessagePlays(context.Background(), message.ID)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error retrieving played state"})
+		return
+	}
+
+	response := struct {
+		Message string            `json:"message"`
+		Data    []dto.MessagePlay `json:"data"`
+	}{
+		Message: "Played state has been retrieved",
+		Data:    dto.NewMessagePlays(plays),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *MessageControllerImpl) AddContactMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	var requestBody struct {
+		ParticipantID int    `json:"participant_id"`
+		SharedUserID  int    `json:"shared_user_id"`
+		VCard         string `json:"vcard"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	if (requestBody.SharedUserID == 0) == (requestBody.VCard == "") {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Either shared_user_id or vcard is required"})
+		return
+	}
+
+	participant, err := c.ConversationRepository.GetParticipantByID(context.Background(), requestBody.ParticipantID)
+	if err != nil || participant.UserID != userID {
+		httputil.WriteResponse(w, http.StatusForbidden, map[string]string{"error": "You are not a participant of this conversation"})
+		return
+	}
+
+	if err := c.Policy.CanSendMessage(context.Background(), userID, participant.ConversationID, ""); err != nil {
+		writePolicyError(w, err, "Error adding message")
+		return
+	}
+
+	var sharedContact *model.SharedContact
+	if requestBody.SharedUserID != 0 {
+		sharedUser, err := c.UserRepository.GetUserByID(context.Background(), requestBody.SharedUserID)
+		if err != nil {
+			httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+			return
+		}
+		linkable, err := c.canLinkContact(context.Background(), userID, sharedUser.ID, []string{sharedUser.EmailHash})
+		if err != nil {
+			httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error adding message"})
+			return
+		}
+		if !linkable {
+			httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+			return
+		}
+		sharedContact = &model.SharedContact{
+			SharedUserID: &sharedUser.ID,
+			FullName:     sharedUser.Username,
+		}
+	} else {
+		card, err := vcard.Parse(requestBody.VCard)
+		if err != nil {
+			httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid vCard: " + err.Error()})
+			return
+		}
+		sharedContact = &model.SharedContact{
+			FullName:     card.FullName,
+			FamilyName:   card.FamilyName,
+			GivenName:    card.GivenName,
+			Phones:       card.Phones,
+			Emails:       card.Emails,
+			Organization: card.Organization,
+		}
+		// Cards exported from this service keep pointing at the registered
+		// user, but the id is client supplied, so it only counts when the card
+		// also carries an email the sender could have found that user by.
+		if card.UserID != 0 && len(card.Emails) > 0 {
+			hashes := make([]string, len(card.Emails))
+			for i, email := range card.Emails {
+				hashes[i] = model.HashEmail(email)
+			}
+			linkable, err := c.canLinkContact(context.Background(), userID, card.UserID, hashes)
+			if err != nil {
+				httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error adding message"})
+				return
+			}
+			if linkable {
+				sharedContact.SharedUserID = &card.UserID
+			}
+		}
+	}
+
+	newMessage := model.Message{
+		ConversationID: participant.ConversationID,
+		ParticipantID:  participant.ID,
+		Kind:           model.MessageKindContact,
+		Text:           sharedContact.FullName,
+		SharedContact:  sharedContact,
+	}
+
+	if err := c.MessageService.Send(context.Background(), &newMessage, userID); err != nil {
+		writePolicyError(w, err, "Error adding message")
+		return
+	}
+
+	response := struct {
+		Message string      `json:"message"`
+		Data    dto.Message `json:"data"`
+	}{
+		Message: "Contact message has been created",
+		Data:    dto.NewMessage(&newMessage),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *MessageControllerImpl) ExportContact(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	message, ok := c.getVisibleMessage(w, r, userID, model.MessageKindContact)
+	if !ok {
+		return
+	}
+
+	sharedContact := message.SharedContact
+	card := vcard.Card{
+		FullName:     sharedContact.FullName,
+		FamilyName:   sharedContact.FamilyName,
+		GivenName:    sharedContact.GivenName,
+		Phones:       sharedContact.Phones,
+		Emails:       sharedContact.Emails,
+		Organization: sharedContact.Organization,
+	}
+	if sharedContact.SharedUserID != nil {
+		card.UserID = *sharedContact.SharedUserID
+	}
+
+	w.Header().Set("Content-Type", "text/vcard; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="contact.vcf"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(card.Encode(r.URL.Query().Get("version"))))
+}
+
+func (c *MessageControllerImpl) StartChatFromContact(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	message, ok := c.getVisibleMessage(w, r, userID, model.MessageKindContact)
+	if !ok {
+		return
+	}
+
+	sharedUserID := message.SharedContact.SharedUserID
+	if sharedUserID == nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Contact is not a registered user"})
+		return
+	}
+	if *sharedUserID == userID {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "You cannot start a chat with yourself"})
+		return
+	}
+
+	conversation, _, err := c.ConversationService.GetOrCreateDirect(context.Background(), userID, *sharedUserID)
+	if err != nil {
+		writePolicyError(w, err, "Error creating conversation")
+		return
+	}
+
+	response := struct {
+		Message string           `json:"message"`
+		Data    dto.Conversation `json:"data"`
+	}{
+		Message: "Conversation has been retrieved",
+		Data:    dto.NewConversation(conversation),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+// DeleteMessage lets senders delete their own messages and admins delete
+// anyone's.
+func (c *MessageControllerImpl) DeleteMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	vars := mux.Vars(r)
+	messageIDstr := vars["id"]
+
+	messageID, err := strconv.Atoi(messageIDstr)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid message id"})
+		return
+	}
+
+	message, err := c.MessageRepository.GetMessageByID(context.Background(), messageID)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": "Message not found"})
+		return
+	}
+
+	sender, err := c.ConversationRepository.GetParticipantByID(context.Background(), message.ParticipantID)
+	if err != nil || sender.UserID != userID {
+		if _, _, err := c.ConversationService.Authorize(context.Background(), message.ConversationID, userID, service.ActionDeleteOthersMessages); err != nil {
+			writeConversationError(w, err, "Error deleting message")
+			return
+		}
+	}
+
+	unpinned, err := c.MessageRepository.DeleteMessage(context.Background(), message.ID)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error deleting message"})
+		return
+	}
+	if unpinned {
+		service.Emit(service.Event{Type: service.EventMessageUnpinned, ConversationID: message.ConversationID, Data: map[string]int{"message_id": message.ID}})
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Message has been deleted",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+// canLinkContact reports whether a shared contact may point at the
+// registered user targetID: the sender must be able to find them by one of
+// the email hashes, and neither may have blocked the other.
+func (c *MessageControllerImpl) canLinkContact(ctx context.Context, senderID int, targetID int, hashes []string) (bool, error) {
+	matches, err := c.UserRepository.GetUsersByEmailHashes(ctx, senderID, hashes)
+	if err != nil {
+		return false, err
+	}
+	found := false
+	for _, match := range matches {
+		if match.ID == targetID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false, nil
+	}
+
+	if err := c.Policy.CanInteract(ctx, senderID, targetID); err != nil {
+		if errors.Is(err, service.ErrBlocked) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *MessageControllerImpl) getVisibleMessage(w http.ResponseWriter, r *http.Request, userID int, kind string) (*model.Message, bool) {
+	vars := mux.Vars(r)
+	messageIDstr := vars["id"]
+
+	messageID, err := strconv.Atoi(messageIDstr)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid message id"})
+		return nil, false
+	}
+
+	message, err := c.MessageRepository.GetMessageByID(context.Background(), messageID)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": "Message not found"})
+		return nil, false
+	}
+
+	if message.Kind != kind || (kind == model.MessageKindContact && message.SharedContact == nil) {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Message is not a " + kind + " message"})
+		return nil, false
+	}
+
+	participant, err := c.ConversationRepository.GetParticipant(context.Background(), message.ConversationID, userID)
+	if err != nil || !participant.CanSee(message.CreatedAt) {
+		httputil.WriteResponse(w, http.StatusForbidden, map[string]string{"error": "You are not a participant of this conversation"})
+		return nil, false
+	}
+
+	return message, true
+}
@@ -3,11 +3,10 @@ package controller
 import (
 	"context"
 	"net/http"
-	"strconv"
 
-	"github.com/gorilla/mux"
-	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/dto"
 	"github.com/messaging-go-service/internal/repository"
+	"github.com/messaging-go-service/middleware"
 	httputil "github.com/messaging-go-service/pkg/http"
 )
 
@@ -26,12 +25,9 @@ func NewNotificationController(notifRepo repository.NotificationRepository) Noti
 }
 
 func (c *NotificationControllerImpl) GetNotificationsByUser(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	userIDstr := vars["user_id"]
-
-	userID, err := strconv.Atoi(userIDstr)
-	if err != nil {
-		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid user id"})
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
 		return
 	}
 
@@ -42,11 +38,11 @@ func (c *NotificationControllerImpl) GetNotificationsByUser(w http.ResponseWrite
 	}
 
 	response := struct {
-		Message string               `json:"message"`
-		Data    []model.Notification `json:"data"`
+		Message string             `json:"message"`
+		Data    []dto.Notification `json:"data"`
 	}{
 		Message: "User posts have been retrieved",
-		Data:    notifications,
+		Data:    dto.NewNotifications(notifications),
 	}
 
 	httputil.WriteResponse(w, http.StatusOK, response)
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+	"github.com/messaging-go-service/internal/dto"
+	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/repository"
+	"github.com/messaging-go-service/middleware"
+)
+
+type notificationRepositoryStub struct {
+	repository.NotificationRepository
+	notifications []model.Notification
+}
+
+func (s *notificationRepositoryStub) GetNotificationsByUserID(ctx context.Context, userID int) ([]model.Notification, error) {
+	var notifications []model.Notification
+	for _, notification := range s.notifications {
+		if notification.UserID == userID {
+			notifications = append(notifications, notification)
+		}
+	}
+	return notifications, nil
+}
+
+func TestGetNotificationsByUser(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", "secret")
+	token := func(userID int) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"id": userID}).SignedString([]byte("secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + signed
+	}
+
+	controller := NewNotificationController(&notificationRepositoryStub{notifications: []model.Notification{
+		{ID: 1, UserID: 1, Message: "bob sent you a message"},
+		{ID: 2, UserID: 2, Message: "alice sent you a message"},
+	}})
+	router := mux.NewRouter()
+	notificationRouter := router.PathPrefix("/api/notification").Subrouter()
+	notificationRouter.Use(middleware.CheckAuth)
+	notificationRouter.HandleFunc("/list", controller.GetNotificationsByUser).Methods("GET")
+
+	tests := []struct {
+		name       string
+		path       string
+		auth       string
+		wantStatus int
+		wantIDs    []int
+	}{
+		{name: "own notifications", path: "/api/notification/list", auth: token(1), wantStatus: http.StatusOK, wantIDs: []int{1}},
+		{name: "other user", path: "/api/notification/list", auth: token(2), wantStatus: http.StatusOK, wantIDs: []int{2}},
+		{name: "user id in path", path: "/api/notification/list/2", auth: token(1), wantStatus: http.StatusNotFound},
+		{name: "no token", path: "/api/notification/list", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.auth != "" {
+				request.Header.Set("Authorization", tt.auth)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data []dto.Notification `json:"data"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			var ids []int
+			for _, notification := range response.Data {
+				ids = append(ids, notification.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("notification ids = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/messaging-go-service/internal/dto"
+	"github.com/messaging-go-service/internal/service"
+	"github.com/messaging-go-service/middleware"
+	httputil "github.com/messaging-go-service/pkg/http"
+)
+
+type PinController interface {
+	PinMessage(w http.ResponseWriter, r *http.Request)
+	UnpinMessage(w http.ResponseWriter, r *http.Request)
+	GetPinnedMessages(w http.ResponseWriter, r *http.Request)
+}
+
+type PinControllerImpl struct {
+	PinService service.PinService
+}
+
+func NewPinController(pinService service.PinService) PinController {
+	return &PinControllerImpl{
+		PinService: pinService,
+	}
+}
+
+func (c *PinControllerImpl) PinMessage(w http.ResponseWriter, r *http.Request) {
+	messageID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid message id"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	pin, err := c.PinService.Pin(context.Background(), messageID, userID)
+	if err != nil {
+		writePinError(w, err, "Error pinning message")
+		return
+	}
+
+	response := struct {
+		Message string            `json:"message"`
+		Data    dto.PinnedMessage `json:"data"`
+	}{
+		Message: "Message has been pinned",
+		Data:    dto.NewPinnedMessage(pin),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *PinControllerImpl) UnpinMessage(w http.ResponseWriter, r *http.Request) {
+	messageID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid message id"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	if err := c.PinService.Unpin(context.Background(), messageID, userID); err != nil {
+		writePinError(w, err, "Error unpinning message")
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Message has been unpinned",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *PinControllerImpl) GetPinnedMessages(w http.ResponseWriter, r *http.Request) {
+	conversationID, ok := readConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	pins, err := c.PinService.GetPinned(context.Background(), conversationID, userID)
+	if err != nil {
+		writePinError(w, err, "Error retrieving pinned messages")
+		return
+	}
+
+	response := struct {
+		Message string              `json:"message"`
+		Data    []dto.PinnedMessage `json:"data"`
+	}{
+		Message: "Pinned messages have been retrieved",
+		Data:    dto.NewPinnedMessages(pins),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func writePinError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrMessageNotFound), errors.Is(err, service.ErrNotPinned):
+		httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrAlreadyPinned), errors.Is(err, service.ErrTooManyPinnedMessages):
+		httputil.WriteResponse(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrNotSubscribed):
+		httputil.WriteResponse(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+	default:
+		writeConversationError(w, err, fallback)
+	}
+}
@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/messaging-go-service/internal/dto"
+	"github.com/messaging-go-service/internal/service"
+	"github.com/messaging-go-service/middleware"
+	httputil "github.com/messaging-go-service/pkg/http"
+)
+
+type ReceiptController interface {
+	MarkRead(w http.ResponseWriter, r *http.Request)
+	MarkDelivered(w http.ResponseWriter, r *http.Request)
+	GetMessageStatus(w http.ResponseWriter, r *http.Request)
+	GetUnreadCounts(w http.ResponseWriter, r *http.Request)
+	GetSeenBy(w http.ResponseWriter, r *http.Request)
+}
+
+type ReceiptControllerImpl struct {
+	ReceiptService service.ReceiptService
+}
+
+func NewReceiptController(receiptService service.ReceiptService) ReceiptController {
+	return &ReceiptControllerImpl{
+		ReceiptService: receiptService,
+	}
+}
+
+func (c *ReceiptControllerImpl) MarkRead(w http.ResponseWriter, r *http.Request) {
+	conversationID, ok := readConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	var requestBody struct {
+		MessageID int `json:"message_id"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil || requestBody.MessageID <= 0 {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "A message id is required"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	receipt, err := c.ReceiptService.MarkRead(context.Background(), conversationID, userID, requestBody.MessageID)
+	if err != nil {
+		writeReceiptError(w, err, "Error marking conversation read")
+		return
+	}
+
+	response := struct {
+		Message string          `json:"message"`
+		Data    dto.MessageRead `json:"data"`
+	}{
+		Message: "Conversation has been marked read",
+		Data:    dto.NewMessageRead(receipt),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+// MarkDelivered is called by a device that received messages through a push
+// notification rather than its connection.
+func (c *ReceiptControllerImpl) MarkDelivered(w http.ResponseWriter, r *http.Request) {
+	conversationID, ok := readConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	var requestBody struct {
+		MessageID int `json:"message_id"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil || requestBody.MessageID <= 0 {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "A message id is required"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	if err := c.ReceiptService.MarkDelivered(context.Background(), conversationID, userID, requestBody.MessageID); err != nil {
+		writeReceiptError(w, err, "Error marking messages delivered")
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Messages have been marked delivered",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ReceiptControllerImpl) GetMessageStatus(w http.ResponseWriter, r *http.Request) {
+	messageID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid message id"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	status, err := c.ReceiptService.GetMessageStatus(context.Background(), messageID, userID)
+	if err != nil {
+		writeReceiptError(w, err, "Error retrieving message status")
+		return
+	}
+
+	response := struct {
+		Message string            `json:"message"`
+		Data    dto.MessageStatus `json:"data"`
+	}{
+		Message: "Message status has been retrieved",
+		Data:    dto.NewMessageStatus(status),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ReceiptControllerImpl) GetUnreadCounts(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	counts, err := c.ReceiptService.GetUnreadCounts(context.Background(), userID)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error retrieving unread counts"})
+		return
+	}
+
+	response := struct {
+		Message string            `json:"message"`
+		Data    []dto.UnreadCount `json:"data"`
+	}{
+		Message: "Unread counts have been retrieved",
+		Data:    dto.NewUnreadCounts(counts),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ReceiptControllerImpl) GetSeenBy(w http.ResponseWriter, r *http.Request) {
+	messageID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid message id"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	receipts, err := c.ReceiptService.GetSeenBy(context.Background(), messageID, userID)
+	if err != nil {
+		writeReceiptError(w, err, "Error retrieving read receipts")
+		return
+	}
+
+	response := struct {
+		Message string            `json:"message"`
+		Data    []dto.MessageRead `json:"data"`
+	}{
+		Message: "Read receipts have been retrieved",
+		Data:    dto.NewMessageReads(receipts),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func writeReceiptError(w http.ResponseWriter, err error, fallback string) {
+	if errors.Is(err, service.ErrMessageNotFound) {
+		httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	writePolicyError(w, err, fallback)
+}
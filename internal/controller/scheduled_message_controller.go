@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/messaging-go-service/internal/dto"
+	"github.com/messaging-go-service/internal/service"
+	"github.com/messaging-go-service/middleware"
+	httputil "github.com/messaging-go-service/pkg/http"
+)
+
+const maxScheduleAhead = 365 * 24 * time.Hour
+
+type ScheduledMessageController interface {
+	ScheduleMessage(w http.ResponseWriter, r *http.Request)
+	GetScheduledMessages(w http.ResponseWriter, r *http.Request)
+	UpdateScheduledMessage(w http.ResponseWriter, r *http.Request)
+	CancelScheduledMessage(w http.ResponseWriter, r *http.Request)
+}
+
+type ScheduledMessageControllerImpl struct {
+	ScheduledMessageService service.ScheduledMessageService
+}
+
+func NewScheduledMessageController(scheduledMessageService service.ScheduledMessageService) ScheduledMessageController {
+	return &ScheduledMessageControllerImpl{
+		ScheduledMessageService: scheduledMessageService,
+	}
+}
+
+func (c *ScheduledMessageControllerImpl) ScheduleMessage(w http.ResponseWriter, r *http.Request) {
+	conversationID, ok := readConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	var requestBody struct {
+		Text   string    `json:"text"`
+		SendAt time.Time `json:"send_at"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil || strings.TrimSpace(requestBody.Text) == "" {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Text is required"})
+		return
+	}
+	if !validSendAt(w, requestBody.SendAt) {
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	scheduled, err := c.ScheduledMessageService.Schedule(context.Background(), conversationID, userID, requestBody.Text, requestBody.SendAt)
+	if err != nil {
+		writeConversationError(w, err, "Error scheduling message")
+		return
+	}
+
+	response := struct {
+		Message string               `json:"message"`
+		Data    dto.ScheduledMessage `json:"data"`
+	}{
+		Message: "Message has been scheduled",
+		Data:    dto.NewScheduledMessage(scheduled),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+// GetScheduledMessages lists the pending messages of the user, optionally
+// only those for the conversation given as ?conversation_id=.
+func (c *ScheduledMessageControllerImpl) GetScheduledMessages(w http.ResponseWriter, r *http.Request) {
+	conversationID := 0
+	if value := r.URL.Query().Get("conversation_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid conversation id"})
+			return
+		}
+		conversationID = id
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	scheduled, err := c.ScheduledMessageService.GetPending(context.Background(), userID, conversationID)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error retrieving scheduled messages"})
+		return
+	}
+
+	response := struct {
+		Message string                 `json:"message"`
+		Data    []dto.ScheduledMessage `json:"data"`
+	}{
+		Message: "Scheduled messages have been retrieved",
+		Data:    dto.NewScheduledMessages(scheduled),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ScheduledMessageControllerImpl) UpdateScheduledMessage(w http.ResponseWriter, r *http.Request) {
+	scheduledID, ok := readScheduledMessageID(w, r)
+	if !ok {
+		return
+	}
+
+	var requestBody struct {
+		Text   *string    `json:"text"`
+		SendAt *time.Time `json:"send_at"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	if requestBody.Text != nil && strings.TrimSpace(*requestBody.Text) == "" {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Text cannot be empty"})
+		return
+	}
+	if requestBody.SendAt != nil && !validSendAt(w, *requestBody.SendAt) {
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	scheduled, err := c.ScheduledMessageService.Update(context.Background(), scheduledID, userID, requestBody.Text, requestBody.SendAt)
+	if err != nil {
+		writeScheduledMessageError(w, err, "Error updating scheduled message")
+		return
+	}
+
+	response := struct {
+		Message string               `json:"message"`
+		Data    dto.ScheduledMessage `json:"data"`
+	}{
+		Message: "Scheduled message has been updated",
+		Data:    dto.NewScheduledMessage(scheduled),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *ScheduledMessageControllerImpl) CancelScheduledMessage(w http.ResponseWriter, r *http.Request) {
+	scheduledID, ok := readScheduledMessageID(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	if err := c.ScheduledMessageService.Cancel(context.Background(), scheduledID, userID); err != nil {
+		writeScheduledMessageError(w, err, "Error cancelling scheduled message")
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Scheduled message has been cancelled",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func readScheduledMessageID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	scheduledID, err := strconv.Atoi(mux.Vars(r)["scheduled_id"])
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid scheduled message id"})
+		return 0, false
+	}
+	return scheduledID, true
+}
+
+func validSendAt(w http.ResponseWriter, sendAt time.Time) bool {
+	now := time.Now()
+	if !sendAt.After(now) {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Send time must be in the future"})
+		return false
+	}
+	if sendAt.After(now.Add(maxScheduleAhead)) {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Send time must be within a year"})
+		return false
+	}
+	return true
+}
+
+func writeScheduledMessageError(w http.ResponseWriter, err error, fallback string) {
+	if errors.Is(err, service.ErrScheduledMessageNotFound) {
+		httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": fallback})
+}
@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/messaging-go-service/internal/dto"
+	"github.com/messaging-go-service/internal/service"
+	"github.com/messaging-go-service/middleware"
+	httputil "github.com/messaging-go-service/pkg/http"
+)
+
+type SearchController interface {
+	SearchMessages(w http.ResponseWriter, r *http.Request)
+}
+
+type SearchControllerImpl struct {
+	SearchService service.SearchService
+}
+
+func NewSearchController(searchService service.SearchService) SearchController {
+	return &SearchControllerImpl{
+		SearchService: searchService,
+	}
+}
+
+func (c *SearchControllerImpl) SearchMessages(w http.ResponseWriter, r *http.Request) {
+	pagination := httputil.ReadPagination(r)
+
+	userID, _ := middleware.GetUserID(r)
+	results, total, err := c.SearchService.SearchMessages(context.Background(), userID, r.URL.Query().Get("q"), pagination.Limit, pagination.Offset())
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSearch) {
+			httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error searching messages"})
+		return
+	}
+	pagination.Total = total
+
+	response := struct {
+		Message    string                    `json:"message"`
+		Data       []dto.MessageSearchResult `json:"data"`
+		Pagination httputil.Pagination       `json:"pagination"`
+	}{
+		Message:    "Messages have been searched",
+		Data:       dto.NewMessageSearchResults(results),
+		Pagination: pagination,
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
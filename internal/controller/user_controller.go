@@ -2,60 +2,74 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/messaging-go-service/internal/dto"
 	"github.com/messaging-go-service/internal/model"
 	"github.com/messaging-go-service/internal/repository"
+	"github.com/messaging-go-service/internal/service"
+	"github.com/messaging-go-service/middleware"
 	httputil "github.com/messaging-go-service/pkg/http"
+	"gorm.io/gorm"
 )
 
 type UserController interface {
 	SearchUsers(w http.ResponseWriter, r *http.Request)
 	GetUserDetail(w http.ResponseWriter, r *http.Request)
+	GetUserByHandle(w http.ResponseWriter, r *http.Request)
 	CreateUser(w http.ResponseWriter, r *http.Request)
 	UpdateUser(w http.ResponseWriter, r *http.Request)
+	ChangeUsername(w http.ResponseWriter, r *http.Request)
+	GetUsernameHistory(w http.ResponseWriter, r *http.Request)
+	GetPresence(w http.ResponseWriter, r *http.Request)
+	UpdatePrivacy(w http.ResponseWriter, r *http.Request)
 }
 
 type UserControllerImpl struct {
-	UserRepository repository.UserRepository
+	UserRepository  repository.UserRepository
+	UserService     service.UserService
+	PresenceService service.PresenceService
 }
 
-func NewUserController(userRepository repository.UserRepository) UserController {
+func NewUserController(userRepository repository.UserRepository, userService service.UserService, presenceService service.PresenceService) UserController {
 	return &UserControllerImpl{
-		UserRepository: userRepository,
+		UserRepository:  userRepository,
+		UserService:     userService,
+		PresenceService: presenceService,
 	}
 }
 
+// SearchUsers finds users matching ?name= by username, the name the viewer
+// saved them under or the start of their email address.
 func (c *UserControllerImpl) SearchUsers(w http.ResponseWriter, r *http.Request) {
-	name := r.URL.Query().Get("name")
-
-	users, err := c.UserRepository.GetAllUsers(context.Background())
-	if err != nil {
-		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if name == "" {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Search query is required"})
 		return
 	}
 
-	var filteredUsers []model.User
-	for _, user := range users {
-		if name == "" || contains(user.Username, name) {
-			filteredUsers = append(filteredUsers, user)
-		}
-	}
+	pagination := httputil.ReadPagination(r)
 
-	if len(filteredUsers) == 0 {
-		httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": "No user found"})
+	viewerID, _ := middleware.GetUserID(r)
+	users, total, err := c.UserRepository.SearchUsers(context.Background(), viewerID, name, pagination.Limit, pagination.Offset())
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error searching users"})
 		return
 	}
+	pagination.Total = total
 
 	response := struct {
-		Message string       `json:"message"`
-		Data    []model.User `json:"data"`
+		Message    string                 `json:"message"`
+		Data       []dto.UserSearchResult `json:"data"`
+		Pagination httputil.Pagination    `json:"pagination"`
 	}{
-		Message: "Search results have been retrieved successfully",
-		Data:    filteredUsers,
+		Message:    "Search results have been retrieved successfully",
+		Data:       dto.NewUserSearchResults(users),
+		Pagination: pagination,
 	}
 	httputil.WriteResponse(w, http.StatusOK, response)
 }
@@ -77,16 +91,47 @@ func (c *UserControllerImpl) GetUserDetail(w http.ResponseWriter, r *http.Reques
 	}
 
 	response := struct {
-		Message string     `json:"message"`
-		Data    model.User `json:"data"`
+		Message string      `json:"message"`
+		Data    interface{} `json:"data"`
+	}{
+		Message: "User detail has been retrieved",
+		Data:    userView(r, user),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+// GetUserByHandle looks a user up by their @username, in any case.
+func (c *UserControllerImpl) GetUserByHandle(w http.ResponseWriter, r *http.Request) {
+	user, err := c.UserService.GetByHandle(context.Background(), mux.Vars(r)["handle"])
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidUsername) {
+			err = gorm.ErrRecordNotFound
+		}
+		writeUserError(w, err, "Error retrieving user")
+		return
+	}
+
+	response := struct {
+		Message string      `json:"message"`
+		Data    interface{} `json:"data"`
 	}{
 		Message: "User detail has been retrieved",
-		Data:    *user,
+		Data:    userView(r, user),
 	}
 
 	httputil.WriteResponse(w, http.StatusOK, response)
 }
 
+// userView shows users their own account details and everyone else the
+// public profile.
+func userView(r *http.Request, user *model.User) interface{} {
+	if viewerID, _ := middleware.GetUserID(r); viewerID == user.ID {
+		return dto.NewSelfUser(user)
+	}
+	return dto.NewPublicUser(user)
+}
+
 func (c *UserControllerImpl) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var requestBody struct {
 		Username string `json:"username"`
@@ -99,23 +144,18 @@ func (c *UserControllerImpl) CreateUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	newUser := model.User{
-		Username: requestBody.Username,
-		Email:    requestBody.Email,
-		Password: requestBody.Password,
-	}
-
-	if err := c.UserRepository.CreateUser(context.Background(), &newUser); err != nil {
-		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error creating user"})
+	newUser, err := c.UserService.Register(context.Background(), requestBody.Username, requestBody.Email, requestBody.Password)
+	if err != nil {
+		writeUserError(w, err, "Error creating user")
 		return
 	}
 
 	response := struct {
-		Message string     `json:"message"`
-		Data    model.User `json:"data"`
+		Message string         `json:"message"`
+		Data    dto.PublicUser `json:"data"`
 	}{
 		Message: "User has been created",
-		Data:    newUser,
+		Data:    dto.NewPublicUser(newUser),
 	}
 
 	httputil.WriteResponse(w, http.StatusOK, response)
@@ -134,7 +174,6 @@ func (c *UserControllerImpl) UpdateUser(w http.ResponseWriter, r *http.Request)
 	var user model.User
 
 	var requestBody struct {
-		Username       string `json:"username"`
 		Desc           string `json:"desc"`
 		Displayname    string `json:"displayname"`
 		ProfilePicture string `json:"profile_picture"`
@@ -146,7 +185,6 @@ func (c *UserControllerImpl) UpdateUser(w http.ResponseWriter, r *http.Request)
 	}
 
 	updatedUser := model.User{
-		Username:       requestBody.Username,
 		Desc:           requestBody.Desc,
 		ProfilePicture: requestBody.ProfilePicture,
 	}
@@ -166,6 +204,139 @@ func (c *UserControllerImpl) UpdateUser(w http.ResponseWriter, r *http.Request)
 	httputil.WriteResponse(w, http.StatusOK, response)
 }
 
-func contains(s, substr string) bool {
-	return len(s) > 0 && len(substr) > 0 && strings.Contains(s, substr)
+// ChangeUsername renames the current user. Usernames are changed here only,
+// not through UpdateUser, so the cooldown and history apply.
+func (c *UserControllerImpl) ChangeUsername(w http.ResponseWriter, r *http.Request) {
+	var requestBody struct {
+		Username string `json:"username"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	user, err := c.UserService.ChangeUsername(context.Background(), userID, requestBody.Username)
+	if err != nil {
+		writeUserError(w, err, "Error changing username")
+		return
+	}
+
+	response := struct {
+		Message string       `json:"message"`
+		Data    dto.SelfUser `json:"data"`
+	}{
+		Message: "Username has been changed",
+		Data:    dto.NewSelfUser(user),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *UserControllerImpl) GetUsernameHistory(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.GetUserID(r)
+	history, err := c.UserService.GetUsernameHistory(context.Background(), userID)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error retrieving username history"})
+		return
+	}
+
+	response := struct {
+		Message string                `json:"message"`
+		Data    []dto.UsernameHistory `json:"data"`
+	}{
+		Message: "Username history has been retrieved",
+		Data:    dto.NewUsernameHistories(history),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *UserControllerImpl) GetPresence(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userIDstr := vars["id"]
+
+	userID, err := strconv.Atoi(userIDstr)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid user id"})
+		return
+	}
+
+	viewerID, _ := middleware.GetUserID(r)
+	presence, err := c.PresenceService.GetPresence(context.Background(), viewerID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.WriteResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+			return
+		}
+		writePolicyError(w, err, "Error retrieving presence")
+		return
+	}
+
+	response := struct {
+		Message string       `json:"message"`
+		Data    dto.Presence `json:"data"`
+	}{
+		Message: "Presence has been retrieved",
+		Data:    dto.NewPresence(presence),
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func (c *UserControllerImpl) UpdatePrivacy(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	var requestBody struct {
+		LastSeenVisibility   *string `json:"last_seen_visibility"`
+		EmailDiscoverability *string `json:"email_discoverability"`
+	}
+
+	if err := httputil.ReadRequest(r, &requestBody); err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if requestBody.LastSeenVisibility != nil && !validVisibility(*requestBody.LastSeenVisibility) {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Last seen visibility must be everyone, contacts or nobody"})
+		return
+	}
+	if requestBody.EmailDiscoverability != nil && !validVisibility(*requestBody.EmailDiscoverability) {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Email discoverability must be everyone, contacts or nobody"})
+		return
+	}
+
+	if requestBody.LastSeenVisibility != nil {
+		if err := c.PresenceService.SetVisibility(context.Background(), userID, *requestBody.LastSeenVisibility); err != nil {
+			httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error updating privacy"})
+			return
+		}
+	}
+	if requestBody.EmailDiscoverability != nil {
+		if err := c.UserRepository.UpdateEmailDiscoverability(context.Background(), userID, *requestBody.EmailDiscoverability); err != nil {
+			httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error updating privacy"})
+			return
+		}
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Privacy has been updated",
+	}
+
+	httputil.WriteResponse(w, http.StatusOK, response)
+}
+
+func validVisibility(visibility string) bool {
+	switch visibility {
+	case model.VisibilityEveryone, model.VisibilityContacts, model.VisibilityNobody:
+		return true
+	}
+	return false
 }
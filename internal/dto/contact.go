@@ -0,0 +1,51 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/messaging-go-service/internal/model"
+)
+
+// Contact is an entry of the requesting user's address book. DisplayName is
+// the name it shows under: the saved name, or else the username.
+type Contact struct {
+	ID            int       `json:"id"`
+	ContactUserID int       `json:"contact_user_id"`
+	SavedName     string    `json:"saved_name"`
+	DisplayName   string    `json:"display_name,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func NewContact(contact *model.Contact) Contact {
+	return Contact{
+		ID:            contact.ID,
+		ContactUserID: contact.ContactUserID,
+		SavedName:     contact.SavedName,
+		DisplayName:   contact.DisplayName,
+		CreatedAt:     contact.CreatedAt,
+		UpdatedAt:     contact.UpdatedAt,
+	}
+}
+
+func NewContacts(contacts []model.Contact) []Contact {
+	return mapAll(contacts, NewContact)
+}
+
+type Block struct {
+	ID            int       `json:"id"`
+	BlockedUserID int       `json:"blocked_user_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func NewBlock(block *model.Block) Block {
+	return Block{
+		ID:            block.ID,
+		BlockedUserID: block.BlockedUserID,
+		CreatedAt:     block.CreatedAt,
+	}
+}
+
+func NewBlocks(blocks []model.Block) []Block {
+	return mapAll(blocks, NewBlock)
+}
@@ -0,0 +1,109 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/messaging-go-service/internal/model"
+)
+
+// Conversation carries the settings of the requesting member, such as Muted
+// and Pinned, alongside the conversation itself.
+type Conversation struct {
+	ID                 int           `json:"id"`
+	Kind               string        `json:"kind"`
+	Title              string        `json:"title"`
+	Description        string        `json:"description"`
+	Avatar             string        `json:"avatar,omitempty"`
+	UserID             int           `json:"user_id"`
+	Public             bool          `json:"public"`
+	OnlyAdminsPost     bool          `json:"only_admins_post"`
+	OnlyAdminsEditInfo bool          `json:"only_admins_edit_info"`
+	OnlyAdminsPin      bool          `json:"only_admins_pin"`
+	SlowModeSeconds    int           `json:"slow_mode_seconds"`
+	MessageTTLSeconds  int           `json:"message_ttl_seconds"`
+	MessageTTLFromRead bool          `json:"message_ttl_from_read"`
+	SubscriberCount    int64         `json:"subscriber_count,omitempty"`
+	DeliveredMessageID int           `json:"delivered_message_id"`
+	ReadMessageID      int           `json:"read_message_id"`
+	LastMessageAt      *time.Time    `json:"last_message_at,omitempty"`
+	LastMessage        *Message      `json:"last_message,omitempty"`
+	UnreadCount        int64         `json:"unread_count"`
+	Muted              bool          `json:"muted"`
+	MutedUntil         *time.Time    `json:"muted_until,omitempty"`
+	Pinned             bool          `json:"pinned"`
+	Archived           bool          `json:"archived"`
+	MarkedUnread       bool          `json:"marked_unread"`
+	Participants       []Participant `json:"participants"`
+	CreatedAt          time.Time     `json:"created_at"`
+	UpdatedAt          time.Time     `json:"updated_at"`
+}
+
+func NewConversation(conversation *model.Conversation) Conversation {
+	result := Conversation{
+		ID:                 conversation.ID,
+		Kind:               conversation.Kind,
+		Title:              conversation.Title,
+		Description:        conversation.Description,
+		Avatar:             conversation.Avatar,
+		UserID:             conversation.UserID,
+		Public:             conversation.Public,
+		OnlyAdminsPost:     conversation.OnlyAdminsPost,
+		OnlyAdminsEditInfo: conversation.OnlyAdminsEditInfo,
+		OnlyAdminsPin:      conversation.OnlyAdminsPin,
+		SlowModeSeconds:    conversation.SlowModeSeconds,
+		MessageTTLSeconds:  conversation.MessageTTLSeconds,
+		MessageTTLFromRead: conversation.MessageTTLFromRead,
+		SubscriberCount:    conversation.SubscriberCount,
+		DeliveredMessageID: conversation.DeliveredMessageID,
+		ReadMessageID:      conversation.ReadMessageID,
+		LastMessageAt:      conversation.LastMessageAt,
+		UnreadCount:        conversation.UnreadCount,
+		Muted:              conversation.Muted,
+		MutedUntil:         conversation.MutedUntil,
+		Pinned:             conversation.Pinned,
+		Archived:           conversation.Archived,
+		MarkedUnread:       conversation.MarkedUnread,
+		Participants:       NewParticipants(conversation.Participants),
+		CreatedAt:          conversation.CreatedAt,
+		UpdatedAt:          conversation.UpdatedAt,
+	}
+	if conversation.LastMessage != nil {
+		message := NewMessage(conversation.LastMessage)
+		result.LastMessage = &message
+	}
+	return result
+}
+
+func NewConversations(conversations []model.Conversation) []Conversation {
+	return mapAll(conversations, NewConversation)
+}
+
+// Participant leaves out the inbox settings of the member, which only they
+// see, as the flags of Conversation.
+type Participant struct {
+	ID             int        `json:"id"`
+	ConversationID int        `json:"conversation_id"`
+	UserID         int        `json:"user_id"`
+	Role           string     `json:"role"`
+	RequestStatus  string     `json:"request_status"`
+	DisplayName    string     `json:"display_name,omitempty"`
+	JoinedAt       time.Time  `json:"joined_at"`
+	LeftAt         *time.Time `json:"left_at,omitempty"`
+}
+
+func NewParticipant(participant *model.Participant) Participant {
+	return Participant{
+		ID:             participant.ID,
+		ConversationID: participant.ConversationID,
+		UserID:         participant.UserID,
+		Role:           participant.Role,
+		RequestStatus:  participant.RequestStatus,
+		DisplayName:    participant.DisplayName,
+		JoinedAt:       participant.JoinedAt,
+		LeftAt:         participant.LeftAt,
+	}
+}
+
+func NewParticipants(participants []model.Participant) []Participant {
+	return mapAll(participants, NewParticipant)
+}
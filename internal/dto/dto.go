@@ -0,0 +1,15 @@
+// Package dto holds the shapes the API returns, so that what clients see does
+// not change with the database schema. Controllers never write model structs
+// directly: each type here has a constructor named after it that copies the
+// fields meant for clients from its model, and a plural one for slices, as in
+// NewMessage and NewMessages. A column added to a model stays private until
+// it is added here.
+package dto
+
+func mapAll[M any, D any](items []M, convert func(*M) D) []D {
+	result := make([]D, len(items))
+	for i := range items {
+		result[i] = convert(&items[i])
+	}
+	return result
+}
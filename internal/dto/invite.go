@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/messaging-go-service/internal/model"
+)
+
+type InviteLink struct {
+	ID               int        `json:"id"`
+	ConversationID   int        `json:"conversation_id"`
+	CreatedByID      int        `json:"created_by_id"`
+	Token            string     `json:"token"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	MaxUses          int        `json:"max_uses"`
+	Uses             int        `json:"uses"`
+	RequiresApproval bool       `json:"requires_approval"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+func NewInviteLink(link *model.InviteLink) InviteLink {
+	return InviteLink{
+		ID:               link.ID,
+		ConversationID:   link.ConversationID,
+		CreatedByID:      link.CreatedByID,
+		Token:            link.Token,
+		ExpiresAt:        link.ExpiresAt,
+		MaxUses:          link.MaxUses,
+		Uses:             link.Uses,
+		RequiresApproval: link.RequiresApproval,
+		RevokedAt:        link.RevokedAt,
+		CreatedAt:        link.CreatedAt,
+	}
+}
+
+func NewInviteLinks(links []model.InviteLink) []InviteLink {
+	return mapAll(links, NewInviteLink)
+}
+
+type JoinRequest struct {
+	ID             int       `json:"id"`
+	ConversationID int       `json:"conversation_id"`
+	UserID         int       `json:"user_id"`
+	InviteLinkID   int       `json:"invite_link_id"`
+	Status         string    `json:"status"`
+	ReviewedByID   int       `json:"reviewed_by_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func NewJoinRequest(request *model.JoinRequest) JoinRequest {
+	return JoinRequest{
+		ID:             request.ID,
+		ConversationID: request.ConversationID,
+		UserID:         request.UserID,
+		InviteLinkID:   request.InviteLinkID,
+		Status:         request.Status,
+		ReviewedByID:   request.ReviewedByID,
+		CreatedAt:      request.CreatedAt,
+		UpdatedAt:      request.UpdatedAt,
+	}
+}
+
+func NewJoinRequests(requests []model.JoinRequest) []JoinRequest {
+	return mapAll(requests, NewJoinRequest)
+}
@@ -0,0 +1,174 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/messaging-go-service/internal/model"
+)
+
+type Message struct {
+	ID             int            `json:"id"`
+	ConversationID int            `json:"conversation_id"`
+	ParticipantID  int            `json:"participant_id"`
+	Kind           string         `json:"kind"`
+	Text           string         `json:"text"`
+	SenderName     string         `json:"sender_name,omitempty"`
+	ViewCount      int64          `json:"view_count,omitempty"`
+	TTLSeconds     int            `json:"ttl_seconds,omitempty"`
+	ExpiresAt      *time.Time     `json:"expires_at,omitempty"`
+	Media          *Media         `json:"media,omitempty"`
+	SharedContact  *SharedContact `json:"shared_contact,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+func NewMessage(message *model.Message) Message {
+	result := Message{
+		ID:             message.ID,
+		ConversationID: message.ConversationID,
+		ParticipantID:  message.ParticipantID,
+		Kind:           message.Kind,
+		Text:           message.Text,
+		SenderName:     message.SenderName,
+		ViewCount:      message.ViewCount,
+		TTLSeconds:     message.TTLSeconds,
+		ExpiresAt:      message.ExpiresAt,
+		CreatedAt:      message.CreatedAt,
+		UpdatedAt:      message.UpdatedAt,
+	}
+	if message.Media != nil {
+		media := NewMedia(message.Media)
+		result.Media = &media
+	}
+	if message.SharedContact != nil {
+		contact := NewSharedContact(message.SharedContact)
+		result.SharedContact = &contact
+	}
+	return result
+}
+
+func NewMessages(messages []model.Message) []Message {
+	return mapAll(messages, NewMessage)
+}
+
+type Media struct {
+	ID         int            `json:"id"`
+	Url        string         `json:"url"`
+	FileName   string         `json:"file_name"`
+	MimeType   string         `json:"mime_type"`
+	Size       int64          `json:"size"`
+	DurationMs int64          `json:"duration_ms,omitempty"`
+	Waveform   model.Waveform `json:"waveform,omitempty"`
+}
+
+func NewMedia(media *model.Media) Media {
+	return Media{
+		ID:         media.ID,
+		Url:        media.Url,
+		FileName:   media.FileName,
+		MimeType:   media.MimeType,
+		Size:       media.Size,
+		DurationMs: media.DurationMs,
+		Waveform:   media.Waveform,
+	}
+}
+
+type SharedContact struct {
+	ID           int      `json:"id"`
+	SharedUserID *int     `json:"shared_user_id,omitempty"`
+	FullName     string   `json:"full_name"`
+	FamilyName   string   `json:"family_name,omitempty"`
+	GivenName    string   `json:"given_name,omitempty"`
+	Phones       []string `json:"phones,omitempty"`
+	Emails       []string `json:"emails,omitempty"`
+	Organization string   `json:"organization,omitempty"`
+}
+
+func NewSharedContact(contact *model.SharedContact) SharedContact {
+	return SharedContact{
+		ID:           contact.ID,
+		SharedUserID: contact.SharedUserID,
+		FullName:     contact.FullName,
+		FamilyName:   contact.FamilyName,
+		GivenName:    contact.GivenName,
+		Phones:       contact.Phones,
+		Emails:       contact.Emails,
+		Organization: contact.Organization,
+	}
+}
+
+type PinnedMessage struct {
+	MessageID      int       `json:"message_id"`
+	ConversationID int       `json:"conversation_id"`
+	PinnedByID     int       `json:"pinned_by_id"`
+	Message        *Message  `json:"message,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func NewPinnedMessage(pin *model.PinnedMessage) PinnedMessage {
+	result := PinnedMessage{
+		MessageID:      pin.MessageID,
+		ConversationID: pin.ConversationID,
+		PinnedByID:     pin.PinnedByID,
+		CreatedAt:      pin.CreatedAt,
+	}
+	if pin.Message != nil {
+		message := NewMessage(pin.Message)
+		result.Message = &message
+	}
+	return result
+}
+
+func NewPinnedMessages(pins []model.PinnedMessage) []PinnedMessage {
+	return mapAll(pins, NewPinnedMessage)
+}
+
+type MessagePlay struct {
+	MessageID int       `json:"message_id"`
+	UserID    int       `json:"user_id"`
+	PlayedAt  time.Time `json:"played_at"`
+}
+
+func NewMessagePlay(play *model.MessagePlay) MessagePlay {
+	return MessagePlay{
+		MessageID: play.MessageID,
+		UserID:    play.UserID,
+		PlayedAt:  play.PlayedAt,
+	}
+}
+
+func NewMessagePlays(plays []model.MessagePlay) []MessagePlay {
+	return mapAll(plays, NewMessagePlay)
+}
+
+// ScheduledMessage carries, once sent, the id of the message it became, and
+// the reason when it could not be sent.
+type ScheduledMessage struct {
+	ID             int       `json:"id"`
+	ConversationID int       `json:"conversation_id"`
+	Text           string    `json:"text"`
+	SendAt         time.Time `json:"send_at"`
+	Status         string    `json:"status"`
+	MessageID      *int      `json:"message_id,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func NewScheduledMessage(message *model.ScheduledMessage) ScheduledMessage {
+	return ScheduledMessage{
+		ID:             message.ID,
+		ConversationID: message.ConversationID,
+		Text:           message.Text,
+		SendAt:         message.SendAt,
+		Status:         message.Status,
+		MessageID:      message.MessageID,
+		Error:          message.Error,
+		CreatedAt:      message.CreatedAt,
+		UpdatedAt:      message.UpdatedAt,
+	}
+}
+
+func NewScheduledMessages(messages []model.ScheduledMessage) []ScheduledMessage {
+	return mapAll(messages, NewScheduledMessage)
+}
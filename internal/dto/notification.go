@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/messaging-go-service/internal/model"
+)
+
+type Notification struct {
+	ID        int        `json:"id"`
+	ActorID   int        `json:"actor_id"`
+	Type      string     `json:"type"`
+	Message   string     `json:"message"`
+	Read      bool       `json:"read"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func NewNotification(notification *model.Notification) Notification {
+	return Notification{
+		ID:        notification.ID,
+		ActorID:   notification.ActorID,
+		Type:      notification.Type,
+		Message:   notification.Message,
+		Read:      notification.Read,
+		ReadAt:    notification.ReadedAt,
+		CreatedAt: notification.CreatedAt,
+	}
+}
+
+func NewNotifications(notifications []model.Notification) []Notification {
+	return mapAll(notifications, NewNotification)
+}
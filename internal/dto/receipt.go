@@ -0,0 +1,77 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/messaging-go-service/internal/model"
+)
+
+type MessageRead struct {
+	ConversationID int       `json:"conversation_id"`
+	UserID         int       `json:"user_id"`
+	MessageID      int       `json:"message_id"`
+	ReadAt         time.Time `json:"read_at"`
+}
+
+func NewMessageRead(read *model.MessageRead) MessageRead {
+	return MessageRead{
+		ConversationID: read.ConversationID,
+		UserID:         read.UserID,
+		MessageID:      read.MessageID,
+		ReadAt:         read.ReadAt,
+	}
+}
+
+func NewMessageReads(reads []model.MessageRead) []MessageRead {
+	return mapAll(reads, NewMessageRead)
+}
+
+type MessageStatus struct {
+	MessageID      int    `json:"message_id"`
+	Status         string `json:"status"`
+	RecipientCount int64  `json:"recipient_count"`
+	DeliveredCount int64  `json:"delivered_count"`
+	ReadCount      int64  `json:"read_count"`
+}
+
+func NewMessageStatus(status *model.MessageStatus) MessageStatus {
+	return MessageStatus{
+		MessageID:      status.MessageID,
+		Status:         status.Status,
+		RecipientCount: status.RecipientCount,
+		DeliveredCount: status.DeliveredCount,
+		ReadCount:      status.ReadCount,
+	}
+}
+
+type MessageStatusChange struct {
+	ConversationID int    `json:"conversation_id"`
+	Status         string `json:"status"`
+	MessageID      int    `json:"message_id"`
+}
+
+func NewMessageStatusChange(change *model.MessageStatusChange) MessageStatusChange {
+	return MessageStatusChange{
+		ConversationID: change.ConversationID,
+		Status:         change.Status,
+		MessageID:      change.MessageID,
+	}
+}
+
+type UnreadCount struct {
+	ConversationID int   `json:"conversation_id"`
+	UnreadCount    int64 `json:"unread_count"`
+	MarkedUnread   bool  `json:"marked_unread"`
+}
+
+func NewUnreadCount(count *model.UnreadCount) UnreadCount {
+	return UnreadCount{
+		ConversationID: count.ConversationID,
+		UnreadCount:    count.UnreadCount,
+		MarkedUnread:   count.MarkedUnread,
+	}
+}
+
+func NewUnreadCounts(counts []model.UnreadCount) []UnreadCount {
+	return mapAll(counts, NewUnreadCount)
+}
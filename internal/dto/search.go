@@ -0,0 +1,60 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/messaging-go-service/internal/model"
+)
+
+// MessageSearchResult is a message matching a search. Snippet is the
+// HTML-escaped text around the matches, which are wrapped in <mark> tags.
+type MessageSearchResult struct {
+	MessageID      int       `json:"message_id"`
+	ConversationID int       `json:"conversation_id"`
+	UserID         int       `json:"user_id"`
+	Kind           string    `json:"kind"`
+	Snippet        string    `json:"snippet"`
+	Rank           float64   `json:"rank"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func NewMessageSearchResult(result *model.MessageSearchResult) MessageSearchResult {
+	return MessageSearchResult{
+		MessageID:      result.MessageID,
+		ConversationID: result.ConversationID,
+		UserID:         result.UserID,
+		Kind:           result.Kind,
+		Snippet:        result.Snippet,
+		Rank:           result.Rank,
+		CreatedAt:      result.CreatedAt,
+	}
+}
+
+func NewMessageSearchResults(results []model.MessageSearchResult) []MessageSearchResult {
+	return mapAll(results, NewMessageSearchResult)
+}
+
+// UserSearchResult is a public profile matching a search, with the name the
+// searcher saved the user under and how closely they matched, from 0 to 1.
+type UserSearchResult struct {
+	PublicUser
+	DisplayName string  `json:"display_name,omitempty"`
+	Score       float64 `json:"score"`
+}
+
+func NewUserSearchResult(result *model.UserSearchResult) UserSearchResult {
+	return UserSearchResult{
+		PublicUser: PublicUser{
+			ID:             result.ID,
+			Username:       result.Username,
+			ProfilePicture: result.ProfilePicture,
+			Desc:           result.Desc,
+		},
+		DisplayName: result.DisplayName,
+		Score:       result.Score,
+	}
+}
+
+func NewUserSearchResults(results []model.UserSearchResult) []UserSearchResult {
+	return mapAll(results, NewUserSearchResult)
+}
@@ -0,0 +1,106 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/messaging-go-service/internal/model"
+)
+
+// PublicUser is the profile anyone may see.
+type PublicUser struct {
+	ID             int    `json:"id"`
+	Username       string `json:"username"`
+	ProfilePicture string `json:"profile_picture"`
+	Desc           string `json:"desc"`
+}
+
+func NewPublicUser(user *model.User) PublicUser {
+	return PublicUser{
+		ID:             user.ID,
+		Username:       user.Username,
+		ProfilePicture: user.ProfilePicture,
+		Desc:           user.Desc,
+	}
+}
+
+func NewPublicUsers(users []model.User) []PublicUser {
+	return mapAll(users, NewPublicUser)
+}
+
+// SelfUser is the profile of the requesting user, with their account
+// details and privacy settings.
+type SelfUser struct {
+	PublicUser
+	Email                string    `json:"email"`
+	LastSeenVisibility   string    `json:"last_seen_visibility"`
+	EmailDiscoverability string    `json:"email_discoverability"`
+	CreatedAt            time.Time `json:"created_at"`
+}
+
+func NewSelfUser(user *model.User) SelfUser {
+	return SelfUser{
+		PublicUser:           NewPublicUser(user),
+		Email:                user.Email,
+		LastSeenVisibility:   user.LastSeenVisibility,
+		EmailDiscoverability: user.EmailDiscoverability,
+		CreatedAt:            user.CreatedAt,
+	}
+}
+
+// AdminUser is the account as seen by operators managing it. Like every
+// view, it never includes the password hash.
+type AdminUser struct {
+	SelfUser
+	LastSeenAt *time.Time `json:"last_seen_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+}
+
+func NewAdminUser(user *model.User) AdminUser {
+	admin := AdminUser{
+		SelfUser:   NewSelfUser(user),
+		LastSeenAt: user.LastSeenAt,
+		UpdatedAt:  user.UpdatedAt,
+	}
+	if user.DeletedAt.Valid {
+		admin.DeletedAt = &user.DeletedAt.Time
+	}
+	return admin
+}
+
+// Presence is whether a user is connected, as shown to another user.
+// LastSeenAt is only set while the user is offline.
+type Presence struct {
+	UserID     int        `json:"user_id"`
+	State      string     `json:"state"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
+
+func NewPresence(presence *model.Presence) Presence {
+	return Presence{
+		UserID:     presence.UserID,
+		State:      presence.State,
+		LastSeenAt: presence.LastSeenAt,
+	}
+}
+
+func NewPresences(presences []model.Presence) []Presence {
+	return mapAll(presences, NewPresence)
+}
+
+// UsernameHistory is a username the user gave up and when.
+type UsernameHistory struct {
+	Username  string    `json:"username"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+func NewUsernameHistory(history *model.UsernameHistory) UsernameHistory {
+	return UsernameHistory{
+		Username:  history.Username,
+		ChangedAt: history.ChangedAt,
+	}
+}
+
+func NewUsernameHistories(histories []model.UsernameHistory) []UsernameHistory {
+	return mapAll(histories, NewUsernameHistory)
+}
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Block struct {
+	gorm.Model
+	ID            int       `gorm:"primary_key;column:id"`
+	UserID        int       `gorm:"column:user_id;uniqueIndex:idx_block_pair"`
+	BlockedUserID int       `gorm:"column:blocked_user_id;uniqueIndex:idx_block_pair;index"`
+	CreatedAt     time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt     time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+}
+
+func (b *Block) TableName() string {
+	return "blocks"
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ChannelSubscription makes a user a reader of a channel. Readers are kept
+// apart from participants, who are the staff posting to the channel, so a
+// channel with many readers adds no per-reader work when a post is made.
+type ChannelSubscription struct {
+	gorm.Model
+	ID             int       `gorm:"primary_key;column:id"`
+	ConversationID int       `gorm:"column:conversation_id;uniqueIndex:idx_channel_subscription"`
+	UserID         int       `gorm:"column:user_id;uniqueIndex:idx_channel_subscription;index"`
+	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+}
+
+func (c *ChannelSubscription) TableName() string {
+	return "channel_subscriptions"
+}
+
+// MessageView records that a user has seen a channel post, so each reader
+// counts once towards Message.ViewCount. It is written for every post a
+// reader scrolls past, so it carries no more than the key.
+type MessageView struct {
+	MessageID int       `gorm:"column:message_id;primaryKey;autoIncrement:false"`
+	UserID    int       `gorm:"column:user_id;primaryKey;autoIncrement:false"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (m *MessageView) TableName() string {
+	return "message_views"
+}
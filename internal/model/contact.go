@@ -10,8 +10,9 @@ type Contact struct {
 	gorm.Model
 	ID            int       `gorm:"primary_key;column:id"`
 	SavedName     string    `gorm:"column:saved_name"`
-	UserID        int       `gorm:"column:user_id"`
-	ContactUserID int       `gorm:"column:contact_user_id"`
+	UserID        int       `gorm:"column:user_id;uniqueIndex:idx_contact_user"`
+	ContactUserID int       `gorm:"column:contact_user_id;uniqueIndex:idx_contact_user"`
+	DisplayName   string    `gorm:"->;-:migration;column:display_name"`
 	CreatedAt     time.Time `gorm:"column:created_at;autoCreateTime"`
 	UpdatedAt     time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
 }
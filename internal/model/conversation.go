@@ -1,21 +1,60 @@
 package model
 
 import (
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+const (
+	ConversationKindDirect  = "direct"
+	ConversationKindGroup   = "group"
+	ConversationKindChannel = "channel"
+)
+
 type Conversation struct {
 	gorm.Model
-	ID           int           `gorm:"primary_key;column:id"`
-	Title        string        `gorm:"column:title"`
-	UserID       int           `gorm:"column:user_id"`
-	Participants []Participant `gorm:"foreignKey:ConversationID"`
-	CreatedAt    time.Time     `gorm:"column:created_at;autoCreateTime"`
-	UpdatedAt    time.Time     `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+	ID                 int           `gorm:"primary_key;column:id"`
+	Kind               string        `gorm:"column:kind;default:group"`
+	Title              string        `gorm:"column:title"`
+	Description        string        `gorm:"column:description"`
+	Avatar             string        `gorm:"column:avatar" json:"avatar,omitempty"`
+	UserID             int           `gorm:"column:user_id"`
+	DirectKey          *string       `gorm:"column:direct_key;uniqueIndex" json:"-"`
+	Public             bool          `gorm:"column:public;default:false"`
+	OnlyAdminsPost     bool          `gorm:"column:only_admins_post;default:false"`
+	OnlyAdminsEditInfo bool          `gorm:"column:only_admins_edit_info;default:true"`
+	OnlyAdminsPin      bool          `gorm:"column:only_admins_pin;default:true"`
+	SlowModeSeconds    int           `gorm:"column:slow_mode_seconds;default:0"`
+	MessageTTLSeconds  int           `gorm:"column:message_ttl_seconds;default:0"`
+	MessageTTLFromRead bool          `gorm:"column:message_ttl_from_read;default:false"`
+	SubscriberCount    int64         `gorm:"column:subscriber_count;default:0"`
+	LastMessageID      *int          `gorm:"column:last_message_id"`
+	LastMessageAt      *time.Time    `gorm:"column:last_message_at;index"`
+	DeliveredMessageID int           `gorm:"column:delivered_message_id;default:0"`
+	ReadMessageID      int           `gorm:"column:read_message_id;default:0"`
+	LastMessage        *Message      `gorm:"-" json:"last_message,omitempty"`
+	UnreadCount        int64         `gorm:"-" json:"unread_count"`
+	Muted              bool          `gorm:"-" json:"muted"`
+	MutedUntil         *time.Time    `gorm:"-" json:"muted_until,omitempty"`
+	Pinned             bool          `gorm:"-" json:"pinned"`
+	Archived           bool          `gorm:"-" json:"archived"`
+	MarkedUnread       bool          `gorm:"-" json:"marked_unread"`
+	Participants       []Participant `gorm:"foreignKey:ConversationID"`
+	CreatedAt          time.Time     `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt          time.Time     `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
 }
 
 func (c *Conversation) TableName() string {
 	return "conversations"
 }
+
+// DirectKey identifies the one-to-one conversation between two users
+// regardless of who started it.
+func DirectKey(userID int, otherUserID int) string {
+	if otherUserID < userID {
+		userID, otherUserID = otherUserID, userID
+	}
+	return fmt.Sprintf("%d:%d", userID, otherUserID)
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	JoinRequestStatusPending  = "pending"
+	JoinRequestStatusApproved = "approved"
+	JoinRequestStatusDeclined = "declined"
+)
+
+type InviteLink struct {
+	gorm.Model
+	ID               int        `gorm:"primary_key;column:id"`
+	ConversationID   int        `gorm:"column:conversation_id;index"`
+	CreatedByID      int        `gorm:"column:created_by_id"`
+	Token            string     `gorm:"column:token;uniqueIndex"`
+	ExpiresAt        *time.Time `gorm:"column:expires_at"`
+	MaxUses          int        `gorm:"column:max_uses;default:0"`
+	Uses             int        `gorm:"column:uses;default:0"`
+	RequiresApproval bool       `gorm:"column:requires_approval;default:false"`
+	RevokedAt        *time.Time `gorm:"column:revoked_at"`
+	CreatedAt        time.Time  `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt        time.Time  `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+}
+
+func (i *InviteLink) TableName() string {
+	return "invite_links"
+}
+
+// Usable reports whether the link can still be used to join. MaxUses of 0
+// means unlimited.
+func (i *InviteLink) Usable(now time.Time) bool {
+	if i.RevokedAt != nil || (i.ExpiresAt != nil && !i.ExpiresAt.After(now)) {
+		return false
+	}
+	return i.MaxUses == 0 || i.Uses < i.MaxUses
+}
+
+type JoinRequest struct {
+	gorm.Model
+	ID             int       `gorm:"primary_key;column:id"`
+	ConversationID int       `gorm:"column:conversation_id;uniqueIndex:idx_join_request"`
+	UserID         int       `gorm:"column:user_id;uniqueIndex:idx_join_request"`
+	InviteLinkID   int       `gorm:"column:invite_link_id"`
+	Status         string    `gorm:"column:status;default:pending"`
+	ReviewedByID   int       `gorm:"column:reviewed_by_id"`
+	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+}
+
+func (j *JoinRequest) TableName() string {
+	return "join_requests"
+}
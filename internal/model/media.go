@@ -1,6 +1,9 @@
 package model
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,16 +11,47 @@ import (
 
 type Media struct {
 	gorm.Model
-	ID        int       `gorm:"primary_key;column:id"`
-	UserID    int       `gorm:"primary_key;column:user_id"`
-	MessageID int       `gorm:"primary_key;column:message_id"`
-	Url       string    `gorm:"column:username"`
-	FileName  string    `gorm:"column:email"`
-	Size      string    `gorm:"column:password"`
-	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
-	UpdatedAt time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+	ID         int       `gorm:"primary_key;column:id"`
+	UserID     int       `gorm:"column:user_id"`
+	MessageID  int       `gorm:"column:message_id;index"`
+	Url        string    `gorm:"column:url"`
+	FileName   string    `gorm:"column:file_name"`
+	MimeType   string    `gorm:"column:mime_type"`
+	Size       int64     `gorm:"column:size"`
+	DurationMs int64     `gorm:"column:duration_ms"`
+	Waveform   Waveform  `gorm:"column:waveform;type:text"`
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt  time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
 }
 
 func (m *Media) TableName() string {
 	return "medias"
 }
+
+// Waveform holds the downsampled amplitude bars of an audio attachment.
+type Waveform []int
+
+func (w Waveform) Value() (driver.Value, error) {
+	if w == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal([]int(w))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (w *Waveform) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*w = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), w)
+	case []byte:
+		return json.Unmarshal(v, w)
+	default:
+		return errors.New("unsupported waveform value")
+	}
+}
@@ -6,15 +6,81 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	MessageKindText    = "text"
+	MessageKindVoice   = "voice"
+	MessageKindContact = "contact"
+	MessageKindSystem  = "system"
+)
+
 type Message struct {
 	gorm.Model
-	ID            int       `gorm:"primary_key;column:id"`
-	ParticipantID int       `gorm:"column:participant_id"`
-	Text          string    `gorm:"column:text"`
-	CreatedAt     time.Time `gorm:"column:created_at;autoCreateTime"`
-	UpdatedAt     time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+	ID             int            `gorm:"primary_key;column:id"`
+	ConversationID int            `gorm:"column:conversation_id;index"`
+	ParticipantID  int            `gorm:"column:participant_id"`
+	Kind           string         `gorm:"column:kind;default:text"`
+	Text           string         `gorm:"column:text"`
+	ViewCount      int64          `gorm:"column:view_count;default:0"`
+	TTLSeconds     int            `gorm:"column:ttl_seconds;default:0" json:"ttl_seconds,omitempty"`
+	ExpiresAt      *time.Time     `gorm:"column:expires_at;index" json:"expires_at,omitempty"`
+	SenderName     string         `gorm:"-" json:"sender_name,omitempty"`
+	Media          *Media         `gorm:"foreignKey:MessageID"`
+	SharedContact  *SharedContact `gorm:"foreignKey:MessageID"`
+	CreatedAt      time.Time      `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt      time.Time      `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
 }
 
 func (c *Message) TableName() string {
 	return "messages"
 }
+
+// BeforeCreate gives the message the disappearing timer of its conversation
+// unless the sender set one for this message. The timer runs from now, or
+// from when a recipient first reads the message if the conversation says so.
+func (c *Message) BeforeCreate(tx *gorm.DB) error {
+	if c.Kind == MessageKindSystem {
+		return nil
+	}
+
+	var conversation Conversation
+	if err := tx.Select("message_ttl_seconds", "message_ttl_from_read").
+		Where("id = ?", c.ConversationID).First(&conversation).Error; err != nil {
+		return err
+	}
+
+	if c.TTLSeconds == 0 {
+		c.TTLSeconds = conversation.MessageTTLSeconds
+	}
+	if c.TTLSeconds > 0 && !conversation.MessageTTLFromRead {
+		expiresAt := time.Now().Add(time.Duration(c.TTLSeconds) * time.Second)
+		c.ExpiresAt = &expiresAt
+	}
+	return nil
+}
+
+// AfterCreate keeps the denormalized last-message pointer of the conversation
+// current, moves the sender's read and delivery watermarks past their own
+// message and brings the conversation back out of the archive of members
+// who have not muted it.
+func (c *Message) AfterCreate(tx *gorm.DB) error {
+	if err := tx.Model(&Conversation{}).
+		Where("id = ? AND (last_message_id IS NULL OR last_message_id < ?)", c.ConversationID, c.ID).
+		Updates(map[string]interface{}{"last_message_id": c.ID, "last_message_at": c.CreatedAt}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&Participant{}).
+		Where("conversation_id = ? AND id <> ? AND archived_at IS NOT NULL AND (muted_until IS NULL OR muted_until <= ?)", c.ConversationID, c.ParticipantID, c.CreatedAt).
+		Update("archived_at", nil).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&Participant{}).
+		Where("id = ? AND last_read_message_id < ?", c.ParticipantID, c.ID).
+		Updates(map[string]interface{}{
+			"last_read_message_id":      c.ID,
+			"last_read_at":              c.CreatedAt,
+			"last_delivered_message_id": c.ID,
+			"marked_unread":             false,
+		}).Error
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type MessagePlay struct {
+	gorm.Model
+	ID        int       `gorm:"primary_key;column:id"`
+	MessageID int       `gorm:"column:message_id;uniqueIndex:idx_message_play"`
+	UserID    int       `gorm:"column:user_id;uniqueIndex:idx_message_play"`
+	PlayedAt  time.Time `gorm:"column:played_at;autoCreateTime"`
+}
+
+func (m *MessagePlay) TableName() string {
+	return "message_plays"
+}
@@ -2,18 +2,47 @@ package model
 
 import (
 	"time"
-
-	"gorm.io/gorm"
 )
 
+// MessageRead reports that a member has read a conversation up to
+// MessageID. Reads are stored as a watermark per participant
+// (Participant.LastReadMessageID) rather than a row per message, so marking
+// a conversation read is a single update however many messages it covers.
 type MessageRead struct {
-	gorm.Model
-	ID        int       `gorm:"primary_key;column:id"`
-	MessageID int       `gorm:"primary_key;column:message_id"`
-	UserID    int       `gorm:"primary_key;column:user_id"`
-	ReadAt    time.Time `gorm:"column:created_at;autoCreateTime"`
+	ConversationID int       `json:"conversation_id"`
+	UserID         int       `json:"user_id"`
+	MessageID      int       `json:"message_id"`
+	ReadAt         time.Time `json:"read_at"`
+}
+
+const (
+	MessageStatusSent      = "sent"
+	MessageStatusDelivered = "delivered"
+	MessageStatusRead      = "read"
+)
+
+// MessageStatus is the three-tick status of a message: sent, delivered to
+// at least one device of every current member, or read by all of them.
+type MessageStatus struct {
+	MessageID      int    `gorm:"-" json:"message_id"`
+	Status         string `gorm:"-" json:"status"`
+	RecipientCount int64  `gorm:"column:recipient_count" json:"recipient_count"`
+	DeliveredCount int64  `gorm:"column:delivered_count" json:"delivered_count"`
+	ReadCount      int64  `gorm:"column:read_count" json:"read_count"`
+}
+
+// MessageStatusChange moves every message of the conversation up to
+// MessageID to Status.
+type MessageStatusChange struct {
+	ConversationID int    `json:"conversation_id"`
+	Status         string `json:"status"`
+	MessageID      int    `json:"message_id"`
 }
 
-func (m *MessageRead) TableName() string {
-	return "message_reads"
+// UnreadCount is how many messages from others a member has not read in a
+// conversation.
+type UnreadCount struct {
+	ConversationID int   `gorm:"column:conversation_id" json:"conversation_id"`
+	UnreadCount    int64 `gorm:"column:unread_count" json:"unread_count"`
+	MarkedUnread   bool  `gorm:"column:marked_unread" json:"marked_unread"`
 }
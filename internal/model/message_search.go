@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// MessageSearch is a parsed message search. Text is in the web search syntax
+// of Postgres, so "quoted phrases" and -excluded words work; the other fields
+// are filters left at their zero value when unused.
+type MessageSearch struct {
+	Text           string
+	FromUserID     int
+	ConversationID int
+	Before         *time.Time
+	After          *time.Time
+	HasMedia       bool
+	HasLink        bool
+}
+
+// MessageSearchResult is a message matching a search. Snippet is the
+// HTML-escaped text of the message around the matches, which are wrapped in
+// <mark> tags.
+type MessageSearchResult struct {
+	MessageID      int       `json:"message_id"`
+	ConversationID int       `json:"conversation_id"`
+	UserID         int       `json:"user_id"`
+	Kind           string    `json:"kind"`
+	Snippet        string    `json:"snippet"`
+	Rank           float64   `json:"rank"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -6,6 +6,11 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	NotificationTypeMessageRequest = "message_request"
+	NotificationTypeMessage        = "message"
+)
+
 type Notification struct {
 	gorm.Model
 	ID        int        `gorm:"primary_key;column:id"`
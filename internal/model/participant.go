@@ -1,21 +1,122 @@
 package model
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+const (
+	RoleOwner    = "owner"
+	RoleAdmin    = "admin"
+	RoleMember   = "member"
+	RoleReadOnly = "read_only"
+)
+
+// MuteForever is the MutedUntil of a conversation muted with no end.
+var MuteForever = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+const (
+	RequestStatusAccepted = "accepted"
+	RequestStatusPending  = "pending"
+	RequestStatusDeclined = "declined"
+)
+
 type Participant struct {
 	gorm.Model
-	ID             int       `gorm:"primary_key;column:id"`
-	ConversationID int       `gorm:"column:conversation_id"`
-	UserID         int       `gorm:"column:user_id"`
-	Messages       []Message `gorm:"foreignKey:ParticipantID"`
-	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime"`
-	UpdatedAt      time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+	ID                     int         `gorm:"primary_key;column:id"`
+	ConversationID         int         `gorm:"column:conversation_id;uniqueIndex:idx_participant_membership"`
+	UserID                 int         `gorm:"column:user_id;uniqueIndex:idx_participant_membership"`
+	Role                   string      `gorm:"column:role;default:member"`
+	RequestStatus          string      `gorm:"column:request_status;default:accepted"`
+	InvitedByID            int         `gorm:"column:invited_by_id"`
+	LastReadMessageID      int         `gorm:"column:last_read_message_id;default:0"`
+	LastReadAt             *time.Time  `gorm:"column:last_read_at"`
+	LastDeliveredMessageID int         `gorm:"column:last_delivered_message_id;default:0"`
+	MutedUntil             *time.Time  `gorm:"column:muted_until"`
+	PinnedAt               *time.Time  `gorm:"column:pinned_at"`
+	PinPosition            int         `gorm:"column:pin_position;default:0"`
+	ArchivedAt             *time.Time  `gorm:"column:archived_at"`
+	MarkedUnread           bool        `gorm:"column:marked_unread;default:false"`
+	JoinedAt               time.Time   `gorm:"column:joined_at"`
+	LeftAt                 *time.Time  `gorm:"column:left_at"`
+	RemovedByID            int         `gorm:"column:removed_by_id;default:0"`
+	HistoryGaps            HistoryGaps `gorm:"column:history_gaps;type:text"`
+	DisplayName            string      `gorm:"-" json:"display_name,omitempty"`
+	Messages               []Message   `gorm:"foreignKey:ParticipantID"`
+	CreatedAt              time.Time   `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt              time.Time   `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
 }
 
 func (c *Participant) TableName() string {
 	return "participants"
 }
+
+func (c *Participant) BeforeCreate(tx *gorm.DB) error {
+	if c.JoinedAt.IsZero() {
+		c.JoinedAt = time.Now()
+	}
+	return nil
+}
+
+// Active reports whether the user is still a member. Former members keep
+// their row so they can read the history up to LeftAt.
+func (c *Participant) Active() bool {
+	return c.LeftAt == nil
+}
+
+func (c *Participant) Muted(now time.Time) bool {
+	return c.MutedUntil != nil && c.MutedUntil.After(now)
+}
+
+// CanSee reports whether a message sent at sentAt is visible to the
+// participant: not after they left, and not while they were away before
+// rejoining.
+func (c *Participant) CanSee(sentAt time.Time) bool {
+	if c.LeftAt != nil && sentAt.After(*c.LeftAt) {
+		return false
+	}
+	for _, gap := range c.HistoryGaps {
+		if sentAt.After(gap.From) && sentAt.Before(gap.Until) {
+			return false
+		}
+	}
+	return true
+}
+
+// HistoryGap is a time a member spent away, from leaving until rejoining.
+type HistoryGap struct {
+	From  time.Time `json:"from"`
+	Until time.Time `json:"until"`
+}
+
+// HistoryGaps holds every absence of a member who left and rejoined.
+type HistoryGaps []HistoryGap
+
+func (g HistoryGaps) Value() (driver.Value, error) {
+	if g == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal([]HistoryGap(g))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (g *HistoryGaps) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*g = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), g)
+	case []byte:
+		return json.Unmarshal(v, g)
+	default:
+		return errors.New("unsupported history gaps value")
+	}
+}
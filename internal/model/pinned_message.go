@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// PinnedMessage pins a message to the top of its conversation. It is kept
+// apart from the message so that editing the message keeps it pinned.
+type PinnedMessage struct {
+	MessageID      int       `gorm:"column:message_id;primaryKey;autoIncrement:false"`
+	ConversationID int       `gorm:"column:conversation_id;index"`
+	PinnedByID     int       `gorm:"column:pinned_by_id"`
+	Message        *Message  `gorm:"foreignKey:MessageID"`
+	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (p *PinnedMessage) TableName() string {
+	return "pinned_messages"
+}
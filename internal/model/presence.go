@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+const (
+	PresenceOnline  = "online"
+	PresenceAway    = "away"
+	PresenceOffline = "offline"
+)
+
+// Presence is whether a user is connected, as shown to another user.
+// LastSeenAt is only set while the user is offline.
+type Presence struct {
+	UserID     int        `json:"user_id"`
+	State      string     `json:"state"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Report struct {
+	gorm.Model
+	ID             int       `gorm:"primary_key;column:id"`
+	UserID         int       `gorm:"column:user_id"`
+	ReportedUserID int       `gorm:"column:reported_user_id;index"`
+	ConversationID int       `gorm:"column:conversation_id"`
+	Reason         string    `gorm:"column:reason"`
+	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+}
+
+func (r *Report) TableName() string {
+	return "reports"
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ScheduledStatusPending   = "pending"
+	ScheduledStatusSending   = "sending"
+	ScheduledStatusSent      = "sent"
+	ScheduledStatusCancelled = "cancelled"
+	ScheduledStatusFailed    = "failed"
+)
+
+// ScheduledMessage is a text message to be sent to a conversation at SendAt.
+// Once sent, MessageID points to the message it became.
+type ScheduledMessage struct {
+	gorm.Model
+	ID             int       `gorm:"primary_key;column:id"`
+	ConversationID int       `gorm:"column:conversation_id;index"`
+	UserID         int       `gorm:"column:user_id;index"`
+	Text           string    `gorm:"column:text"`
+	SendAt         time.Time `gorm:"column:send_at;index:idx_scheduled_due,where:status = 'pending'"`
+	Status         string    `gorm:"column:status;default:pending"`
+	MessageID      *int      `gorm:"column:message_id"`
+	Error          string    `gorm:"column:error"`
+	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+}
+
+func (s *ScheduledMessage) TableName() string {
+	return "scheduled_messages"
+}
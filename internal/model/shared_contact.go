@@ -1,6 +1,9 @@
 package model
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,13 +11,47 @@ import (
 
 type SharedContact struct {
 	gorm.Model
-	ID           int       `gorm:"primary_key;column:id"`
-	SharedUserID string    `gorm:"column:shared_user_id"`
-	MessageID    string    `gorm:"column:message_id"`
-	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime"`
-	UpdatedAt    time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+	ID           int        `gorm:"primary_key;column:id"`
+	MessageID    int        `gorm:"column:message_id;index"`
+	SharedUserID *int       `gorm:"column:shared_user_id;index"`
+	FullName     string     `gorm:"column:full_name"`
+	FamilyName   string     `gorm:"column:family_name"`
+	GivenName    string     `gorm:"column:given_name"`
+	Phones       StringList `gorm:"column:phones;type:text"`
+	Emails       StringList `gorm:"column:emails;type:text"`
+	Organization string     `gorm:"column:organization"`
+	CreatedAt    time.Time  `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt    time.Time  `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
 }
 
 func (s *SharedContact) TableName() string {
 	return "shared_contacts"
 }
+
+// StringList stores a list of strings as a JSON array in a text column.
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	case []byte:
+		return json.Unmarshal(v, l)
+	default:
+		return errors.New("unsupported string list value")
+	}
+}
@@ -1,26 +1,67 @@
 package model
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// LastSeenVisibility values decide who may see when a user is online and
+// when they were last seen, EmailDiscoverability values who may find them by
+// their email address.
+const (
+	VisibilityEveryone = "everyone"
+	VisibilityContacts = "contacts"
+	VisibilityNobody   = "nobody"
+)
+
 type User struct {
 	gorm.Model
-	ID             int            `gorm:"primary_key;column:id"`
-	Username       string         `gorm:"column:username"`
-	Email          string         `gorm:"column:email"`
-	Password       string         `gorm:"column:password"`
-	ProfilePicture string         `gorm:"column:profile_picture"`
-	Desc           string         `gorm:"column:description"`
-	CreatedAt      time.Time      `gorm:"column:created_at;autoCreateTime"`
-	UpdatedAt      time.Time      `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
-	Conversations  []Conversation `gorm:"foreignKey:UserID"`
-	Participants   []Participant  `gorm:"foreignKey:UserID"`
-	Notifications  []Notification `gorm:"foreignKey:UserID"`
+	ID                   int            `gorm:"primary_key;column:id"`
+	Username             string         `gorm:"column:username"`
+	Email                string         `gorm:"column:email"`
+	EmailHash            string         `gorm:"column:email_hash;index" json:"-"`
+	Password             string         `gorm:"column:password" json:"-"`
+	ProfilePicture       string         `gorm:"column:profile_picture"`
+	Desc                 string         `gorm:"column:description"`
+	LastSeenAt           *time.Time     `gorm:"column:last_seen_at" json:"-"`
+	LastSeenVisibility   string         `gorm:"column:last_seen_visibility;default:everyone"`
+	EmailDiscoverability string         `gorm:"column:email_discoverability;default:contacts"`
+	UsernameChangedAt    *time.Time     `gorm:"column:username_changed_at" json:"-"`
+	CreatedAt            time.Time      `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt            time.Time      `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+	Conversations        []Conversation `gorm:"foreignKey:UserID"`
+	Participants         []Participant  `gorm:"foreignKey:UserID"`
+	Notifications        []Notification `gorm:"foreignKey:UserID"`
 }
 
 func (u *User) TableName() string {
 	return "users"
 }
+
+func (u *User) BeforeCreate(tx *gorm.DB) error {
+	u.EmailHash = HashEmail(u.Email)
+	return nil
+}
+
+// HashEmail returns the stored hash of an email address: KeyEmailHash of
+// the hex SHA-256 of the normalized address. Clients hash their address book
+// with plain SHA-256, which the server keys before matching.
+func HashEmail(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return KeyEmailHash(hex.EncodeToString(sum[:]))
+}
+
+// KeyEmailHash turns a client-side email hash into the stored one with an
+// HMAC under EMAIL_HASH_KEY, so leaked hashes cannot be reversed by hashing
+// a dictionary of addresses without the key.
+func KeyEmailHash(clientHash string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("EMAIL_HASH_KEY")))
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(clientHash))))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,13 @@
+package model
+
+// UserSearchResult is a user matching a search. DisplayName is the name the
+// searcher saved them under, if any, and Score how closely the best of the
+// username, saved name and email matched, from 0 to 1.
+type UserSearchResult struct {
+	ID             int     `json:"id"`
+	Username       string  `json:"username"`
+	DisplayName    string  `json:"display_name,omitempty"`
+	ProfilePicture string  `json:"profile_picture"`
+	Desc           string  `json:"desc"`
+	Score          float64 `json:"score"`
+}
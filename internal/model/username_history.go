@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UsernameHistory records a username a user gave up and when.
+type UsernameHistory struct {
+	gorm.Model
+	ID        int       `gorm:"primary_key;column:id"`
+	UserID    int       `gorm:"column:user_id;index"`
+	Username  string    `gorm:"column:username"`
+	ChangedAt time.Time `gorm:"column:changed_at"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+}
+
+func (h *UsernameHistory) TableName() string {
+	return "username_histories"
+}
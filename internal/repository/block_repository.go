@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/messaging-go-service/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BlockRepository interface {
+	CreateBlock(ctx context.Context, block *model.Block) error
+	DeleteBlock(ctx context.Context, userID int, blockedUserID int) error
+	GetBlocksByUserID(ctx context.Context, userID int, limit int, offset int) ([]model.Block, int64, error)
+	GetBlockedUserIDs(ctx context.Context, userID int) ([]int, error)
+	GetBlockersOf(ctx context.Context, blockedUserID int, userIDs []int) ([]int, error)
+	IsBlocked(ctx context.Context, userID int, blockedUserID int) (bool, error)
+}
+
+type BlockRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewBlockRepository(db *gorm.DB) BlockRepository {
+	return &BlockRepositoryImpl{db: db}
+}
+
+func (r *BlockRepositoryImpl) CreateBlock(ctx context.Context, block *model.Block) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(block).Error
+}
+
+func (r *BlockRepositoryImpl) DeleteBlock(ctx context.Context, userID int, blockedUserID int) error {
+	return r.db.WithContext(ctx).Unscoped().Where("user_id = ? AND blocked_user_id = ?", userID, blockedUserID).Delete(&model.Block{}).Error
+}
+
+func (r *BlockRepositoryImpl) GetBlocksByUserID(ctx context.Context, userID int, limit int, offset int) ([]model.Block, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&model.Block{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var blocks []model.Block
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).Offset(offset).Find(&blocks).Error; err != nil {
+		return nil, 0, err
+	}
+	return blocks, total, nil
+}
+
+func (r *BlockRepositoryImpl) GetBlockedUserIDs(ctx context.Context, userID int) ([]int, error) {
+	var ids []int
+	if err := r.db.WithContext(ctx).Model(&model.Block{}).Where("user_id = ?", userID).Pluck("blocked_user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *BlockRepositoryImpl) GetBlockersOf(ctx context.Context, blockedUserID int, userIDs []int) ([]int, error) {
+	var ids []int
+	if len(userIDs) == 0 {
+		return ids, nil
+	}
+	if err := r.db.WithContext(ctx).Model(&model.Block{}).Where("blocked_user_id = ? AND user_id IN ?", blockedUserID, userIDs).Pluck("user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *BlockRepositoryImpl) IsBlocked(ctx context.Context, userID int, blockedUserID int) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.Block{}).Where("user_id = ? AND blocked_user_id = ?", userID, blockedUserID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/messaging-go-service/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ChannelRepository interface {
+	Subscribe(ctx context.Context, conversationID int, userID int) error
+	Unsubscribe(ctx context.Context, conversationID int, userID int) error
+	IsSubscribed(ctx context.Context, conversationID int, userID int) (bool, error)
+	SearchChannels(ctx context.Context, query string, limit int, offset int) ([]model.Conversation, int64, error)
+	GetSubscribedChannels(ctx context.Context, userID int, limit int, offset int) ([]model.Conversation, int64, error)
+	GetPosts(ctx context.Context, conversationID int, limit int, offset int) ([]model.Message, int64, error)
+	RecordViews(ctx context.Context, conversationID int, userID int, messageIDs []int) error
+}
+
+type ChannelRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewChannelRepository(db *gorm.DB) ChannelRepository {
+	return &ChannelRepositoryImpl{db: db}
+}
+
+// Subscribe adds the subscription and bumps the channel's subscriber count in
+// the same transaction, leaving both untouched if the user is already
+// subscribed.
+func (r *ChannelRepositoryImpl) Subscribe(ctx context.Context, conversationID int, userID int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		subscription := model.ChannelSubscription{ConversationID: conversationID, UserID: userID}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&subscription)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&model.Conversation{}).Where("id = ?", conversationID).
+			Update("subscriber_count", gorm.Expr("subscriber_count + 1")).Error
+	})
+}
+
+func (r *ChannelRepositoryImpl) Unsubscribe(ctx context.Context, conversationID int, userID int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("conversation_id = ? AND user_id = ?", conversationID, userID).Delete(&model.ChannelSubscription{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&model.Conversation{}).Where("id = ?", conversationID).
+			Update("subscriber_count", gorm.Expr("subscriber_count - 1")).Error
+	})
+}
+
+func (r *ChannelRepositoryImpl) IsSubscribed(ctx context.Context, conversationID int, userID int) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.ChannelSubscription{}).Where("conversation_id = ? AND user_id = ?", conversationID, userID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// SearchChannels finds public channels by title, most subscribed first.
+func (r *ChannelRepositoryImpl) SearchChannels(ctx context.Context, query string, limit int, offset int) ([]model.Conversation, int64, error) {
+	channels := r.db.WithContext(ctx).Model(&model.Conversation{}).
+		Where("kind = ? AND public = ?", model.ConversationKindChannel, true)
+	if query != "" {
+		channels = channels.Where("title ILIKE ?", "%"+escapeLike(query)+"%")
+	}
+
+	var total int64
+	if err := channels.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var conversations []model.Conversation
+	if err := channels.Session(&gorm.Session{}).Order("subscriber_count DESC, id").Limit(limit).Offset(offset).Find(&conversations).Error; err != nil {
+		return nil, 0, err
+	}
+	return conversations, total, nil
+}
+
+func (r *ChannelRepositoryImpl) GetSubscribedChannels(ctx context.Context, userID int, limit int, offset int) ([]model.Conversation, int64, error) {
+	subscribed := r.db.Model(&model.ChannelSubscription{}).Select("conversation_id").Where("user_id = ?", userID)
+	channels := r.db.WithContext(ctx).Model(&model.Conversation{}).Where("id IN (?)", subscribed)
+
+	var total int64
+	if err := channels.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var conversations []model.Conversation
+	if err := channels.Session(&gorm.Session{}).Order("last_message_at DESC NULLS LAST, id DESC").Limit(limit).Offset(offset).Find(&conversations).Error; err != nil {
+		return nil, 0, err
+	}
+	return conversations, total, nil
+}
+
+// GetPosts returns the channel's posts, newest first.
+func (r *ChannelRepositoryImpl) GetPosts(ctx context.Context, conversationID int, limit int, offset int) ([]model.Message, int64, error) {
+	posts := r.db.WithContext(ctx).Model(&model.Message{}).Where("conversation_id = ?", conversationID)
+
+	var total int64
+	if err := posts.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var messages []model.Message
+	if err := posts.Session(&gorm.Session{}).Preload("Media").Preload("SharedContact").Order("id DESC").Limit(limit).Offset(offset).Find(&messages).Error; err != nil {
+		return nil, 0, err
+	}
+	return messages, total, nil
+}
+
+// RecordViews counts the user once towards the view count of each post in
+// messageIDs that belongs to the channel. Posts they have already viewed are
+// skipped by the primary key of message_views.
+func (r *ChannelRepositoryImpl) RecordViews(ctx context.Context, conversationID int, userID int, messageIDs []int) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Exec(`WITH viewed AS (
+			INSERT INTO message_views (message_id, user_id, created_at)
+			SELECT id, ?, NOW() FROM messages WHERE id IN ? AND conversation_id = ? AND deleted_at IS NULL
+			ON CONFLICT DO NOTHING
+			RETURNING message_id
+		)
+		UPDATE messages SET view_count = view_count + 1 WHERE id IN (SELECT message_id FROM viewed)`,
+		userID, messageIDs, conversationID).Error
+}
+
+// escapeLike makes user input match literally inside a LIKE pattern.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/messaging-go-service/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	ContactSortName   = "name"
+	ContactSortRecent = "recent"
+)
+
+type ContactRepository interface {
+	CreateContact(ctx context.Context, contact *model.Contact) error
+	CreateContacts(ctx context.Context, contacts []model.Contact) error
+	GetContactByID(ctx context.Context, id int) (*model.Contact, error)
+	GetContactsByUserID(ctx context.Context, userID int, sort string, limit int, offset int) ([]model.Contact, int64, error)
+	GetContactsByContactUserIDs(ctx context.Context, userID int, contactUserIDs []int) ([]model.Contact, error)
+	UpdateContact(ctx context.Context, id int, contact *model.Contact) error
+	DeleteContact(ctx context.Context, id int) error
+}
+
+type ContactRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewContactRepository(db *gorm.DB) ContactRepository {
+	return &ContactRepositoryImpl{db: db}
+}
+
+func (r *ContactRepositoryImpl) CreateContact(ctx context.Context, contact *model.Contact) error {
+	return r.db.WithContext(ctx).Create(contact).Error
+}
+
+func (r *ContactRepositoryImpl) CreateContacts(ctx context.Context, contacts []model.Contact) error {
+	if len(contacts) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&contacts).Error
+}
+
+func (r *ContactRepositoryImpl) GetContactByID(ctx context.Context, id int) (*model.Contact, error) {
+	var contact model.Contact
+	if err := r.withDisplayName(ctx).First(&contact, "contacts.id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+func (r *ContactRepositoryImpl) GetContactsByUserID(ctx context.Context, userID int, sort string, limit int, offset int) ([]model.Contact, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&model.Contact{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order := "LOWER(" + displayNameColumn + "), contacts.id"
+	if sort == ContactSortRecent {
+		order = "contacts.created_at DESC, contacts.id DESC"
+	}
+
+	var contacts []model.Contact
+	if err := r.withDisplayName(ctx).Where("contacts.user_id = ?", userID).Order(order).Limit(limit).Offset(offset).Find(&contacts).Error; err != nil {
+		return nil, 0, err
+	}
+	return contacts, total, nil
+}
+
+func (r *ContactRepositoryImpl) GetContactsByContactUserIDs(ctx context.Context, userID int, contactUserIDs []int) ([]model.Contact, error) {
+	var contacts []model.Contact
+	if len(contactUserIDs) == 0 {
+		return contacts, nil
+	}
+	if err := r.withDisplayName(ctx).Where("contacts.user_id = ? AND contacts.contact_user_id IN ?", userID, contactUserIDs).Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+	return contacts, nil
+}
+
+func (r *ContactRepositoryImpl) UpdateContact(ctx context.Context, id int, contact *model.Contact) error {
+	return r.db.WithContext(ctx).Model(&model.Contact{}).Where("id = ?", id).Select("saved_name").Updates(contact).Error
+}
+
+func (r *ContactRepositoryImpl) DeleteContact(ctx context.Context, id int) error {
+	// Contacts are hard deleted so the same user can be saved again later.
+	return r.db.WithContext(ctx).Unscoped().Delete(&model.Contact{}, id).Error
+}
+
+const displayNameColumn = "COALESCE(NULLIF(contacts.saved_name, ''), users.username)"
+
+func (r *ContactRepositoryImpl) withDisplayName(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Select("contacts.*, " + displayNameColumn + " AS display_name").
+		Joins("JOIN users ON users.id = contacts.contact_user_id")
+}
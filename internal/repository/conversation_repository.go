@@ -2,19 +2,45 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/messaging-go-service/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ConversationRepository interface {
 	CreateConversation(ctx context.Context, conversation *model.Conversation) error
-	GetConversationsByUserID(ctx context.Context, userID int) ([]model.Conversation, error)
+	GetOrCreateDirectConversation(ctx context.Context, conversation *model.Conversation) (bool, error)
+	GetConversationsByUserID(ctx context.Context, userID int, archived bool, limit int, offset int) ([]model.Conversation, int64, error)
+	GetRequestConversationsByUserID(ctx context.Context, userID int) ([]model.Conversation, error)
 	DeleteConversation(ctx context.Context, id int) error
+	UpdateConversation(ctx context.Context, id int, updates map[string]interface{}) error
 	GetConversationDetailByID(ctx context.Context, id int) (*model.Conversation, error)
+	IsAvatar(ctx context.Context, url string) (bool, error)
 	AddParticipant(ctx context.Context, participant *model.Participant) error
+	RemoveParticipant(ctx context.Context, participantID int, removedByID int, leftAt time.Time) error
+	GetParticipantByID(ctx context.Context, id int) (*model.Participant, error)
+	GetParticipant(ctx context.Context, conversationID int, userID int) (*model.Participant, error)
+	GetParticipantsByConversationID(ctx context.Context, conversationID int) ([]model.Participant, error)
+	UpdateParticipantRequestStatus(ctx context.Context, participantID int, status string) error
+	UpdateParticipantRole(ctx context.Context, participantID int, role string) error
+	UpdateParticipantSettings(ctx context.Context, participantID int, settings map[string]interface{}) error
+	MarkRead(ctx context.Context, participantID int, messageID int, readAt time.Time) (bool, error)
+	MarkDelivered(ctx context.Context, participantID int, messageID int) (bool, error)
+	AdvanceMessageStatus(ctx context.Context, conversationID int) ([]model.MessageStatusChange, error)
+	GetMessageStatus(ctx context.Context, message *model.Message) (*model.MessageStatus, error)
+	GetUnreadCounts(ctx context.Context, userID int) ([]model.UnreadCount, error)
+	GetReaders(ctx context.Context, conversationID int, messageID int, senderParticipantID int) ([]model.Participant, error)
+	GetSenderUserIDs(ctx context.Context, conversationID int, afterMessageID int, upToMessageID int) ([]int, error)
+	PinConversation(ctx context.Context, participantID int, userID int) error
+	CountPinnedByUserID(ctx context.Context, userID int) (int64, error)
+	ReorderPins(ctx context.Context, userID int, conversationIDs []int) error
+	TransferOwnership(ctx context.Context, conversationID int, fromParticipantID int, toParticipantID int) error
+	CountMessagesByParticipantID(ctx context.Context, participantID int) (int64, error)
+	GetLastMessageAt(ctx context.Context, participantID int) (*time.Time, error)
 	AddMessage(ctx context.Context, message *model.Message) error
-	GetMessagesByConversationID(ctx context.Context, conversationID int) ([]model.Message, error)
+	GetMessagesByConversationID(ctx context.Context, participant *model.Participant) ([]model.Message, error)
 }
 
 type ConversationRepositoryImpl struct {
@@ -31,37 +57,495 @@ func (r *ConversationRepositoryImpl) CreateConversation(ctx context.Context, con
 	return r.db.WithContext(ctx).Create(conversation).Error
 }
 
-func (r *ConversationRepositoryImpl) GetConversationsByUserID(ctx context.Context, userID int) ([]model.Conversation, error) {
+// GetOrCreateDirectConversation inserts the direct conversation unless one
+// with the same DirectKey exists, in which case conversation is replaced by
+// the existing one. The unique index on direct_key makes this safe against
+// two users opening the chat at the same time. It reports whether the
+// conversation was created.
+func (r *ConversationRepositoryImpl) GetOrCreateDirectConversation(ctx context.Context, conversation *model.Conversation) (bool, error) {
+	created := false
+	participants := conversation.Participants
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "direct_key"}}, DoNothing: true}).
+			Omit("Participants").Create(conversation)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return tx.Preload("Participants").Where("direct_key = ?", conversation.DirectKey).First(conversation).Error
+		}
+
+		created = true
+		for i := range participants {
+			participants[i].ConversationID = conversation.ID
+		}
+		if err := tx.Create(&participants).Error; err != nil {
+			return err
+		}
+		conversation.Participants = participants
+		return nil
+	})
+
+	return created, err
+}
+
+type inboxRow struct {
+	ConversationID int
+	UnreadCount    int64
+	MutedUntil     *time.Time
+	PinnedAt       *time.Time
+	ArchivedAt     *time.Time
+	MarkedUnread   bool
+}
+
+// GetConversationsByUserID returns the inbox of a user, or their archive
+// when archived is set: every conversation they accepted membership of,
+// pinned ones first in the order the user gave them and the rest by last
+// activity. Each page costs a fixed number of queries regardless of size.
+func (r *ConversationRepositoryImpl) GetConversationsByUserID(ctx context.Context, userID int, archived bool, limit int, offset int) ([]model.Conversation, int64, error) {
+	memberships := r.db.WithContext(ctx).
+		Table("participants").
+		Joins("JOIN conversations ON conversations.id = participants.conversation_id AND conversations.deleted_at IS NULL").
+		Where("participants.user_id = ? AND participants.request_status = ? AND participants.left_at IS NULL AND participants.deleted_at IS NULL", userID, model.RequestStatusAccepted)
+	if archived {
+		memberships = memberships.Where("participants.archived_at IS NOT NULL")
+	} else {
+		memberships = memberships.Where("participants.archived_at IS NULL")
+	}
+
+	var total int64
+	if err := memberships.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []inboxRow
+	if err := memberships.Session(&gorm.Session{}).
+		Select(`participants.conversation_id, participants.muted_until, participants.pinned_at, participants.archived_at, participants.marked_unread,
+			(SELECT COUNT(*) FROM messages WHERE messages.conversation_id = participants.conversation_id
+				AND messages.id > participants.last_read_message_id
+				AND messages.participant_id <> participants.id
+				AND messages.deleted_at IS NULL) AS unread_count`).
+		Order("participants.pinned_at IS NULL, participants.pin_position, conversations.last_message_at DESC NULLS LAST, conversations.id DESC").
+		Limit(limit).Offset(offset).
+		Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	conversationIDs := make([]int, 0, len(rows))
+	for _, row := range rows {
+		conversationIDs = append(conversationIDs, row.ConversationID)
+	}
+
 	var conversations []model.Conversation
-	if err := r.db.Where("user_id = ?", userID).Find(&conversations).Error; err != nil {
+	if len(conversationIDs) > 0 {
+		if err := r.db.WithContext(ctx).Preload("Participants", "left_at IS NULL").Where("id IN ?", conversationIDs).Find(&conversations).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+
+	var lastMessageIDs []int
+	for _, conversation := range conversations {
+		if conversation.LastMessageID != nil {
+			lastMessageIDs = append(lastMessageIDs, *conversation.LastMessageID)
+		}
+	}
+
+	var lastMessages []model.Message
+	if len(lastMessageIDs) > 0 {
+		if err := r.db.WithContext(ctx).Preload("Media").Where("id IN ?", lastMessageIDs).Find(&lastMessages).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+
+	byID := make(map[int]model.Conversation, len(conversations))
+	for _, conversation := range conversations {
+		byID[conversation.ID] = conversation
+	}
+	messagesByID := make(map[int]model.Message, len(lastMessages))
+	for _, message := range lastMessages {
+		messagesByID[message.ID] = message
+	}
+
+	now := time.Now()
+	inbox := make([]model.Conversation, 0, len(rows))
+	for _, row := range rows {
+		conversation, ok := byID[row.ConversationID]
+		if !ok {
+			continue
+		}
+		if conversation.LastMessageID != nil {
+			if message, ok := messagesByID[*conversation.LastMessageID]; ok {
+				conversation.LastMessage = &message
+			}
+		}
+		conversation.UnreadCount = row.UnreadCount
+		conversation.Muted = row.MutedUntil != nil && row.MutedUntil.After(now)
+		if conversation.Muted {
+			conversation.MutedUntil = row.MutedUntil
+		}
+		conversation.Pinned = row.PinnedAt != nil
+		conversation.Archived = row.ArchivedAt != nil
+		conversation.MarkedUnread = row.MarkedUnread
+		inbox = append(inbox, conversation)
+	}
+
+	return inbox, total, nil
+}
+
+func (r *ConversationRepositoryImpl) GetRequestConversationsByUserID(ctx context.Context, userID int) ([]model.Conversation, error) {
+	var conversations []model.Conversation
+	pending := r.db.Model(&model.Participant{}).Select("conversation_id").
+		Where("user_id = ? AND request_status = ? AND left_at IS NULL", userID, model.RequestStatusPending)
+	if err := r.db.WithContext(ctx).Preload("Participants", "left_at IS NULL").Where("id IN (?)", pending).Order("created_at DESC").Find(&conversations).Error; err != nil {
 		return nil, err
 	}
 	return conversations, nil
 }
 
 func (r *ConversationRepositoryImpl) DeleteConversation(ctx context.Context, id int) error {
-	return r.db.Delete(&model.Conversation{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Release the direct key so the two users can start a new chat later.
+		if err := tx.Model(&model.Conversation{}).Where("id = ?", id).Update("direct_key", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Conversation{}, id).Error
+	})
+}
+
+// GetLastMessageAt returns when the participant last posted, counting
+// messages they have since deleted and ignoring system notices.
+func (r *ConversationRepositoryImpl) GetLastMessageAt(ctx context.Context, participantID int) (*time.Time, error) {
+	var lastSentAt *time.Time
+	err := r.db.WithContext(ctx).Unscoped().Model(&model.Message{}).
+		Where("participant_id = ? AND kind <> ?", participantID, model.MessageKindSystem).
+		Select("MAX(created_at)").Row().Scan(&lastSentAt)
+	if err != nil {
+		return nil, err
+	}
+	return lastSentAt, nil
 }
 
 func (r *ConversationRepositoryImpl) AddMessage(ctx context.Context, message *model.Message) error {
 	return r.db.WithContext(ctx).Create(message).Error
 }
 
+// GetConversationDetailByID loads the conversation with its current members.
 func (r *ConversationRepositoryImpl) GetConversationDetailByID(ctx context.Context, id int) (*model.Conversation, error) {
 	var conversation model.Conversation
-	if err := r.db.WithContext(ctx).First(&conversation, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Participants", "left_at IS NULL").First(&conversation, id).Error; err != nil {
 		return nil, err
 	}
 	return &conversation, nil
 }
 
+// AddParticipant adds the user to the conversation, reviving their previous
+// membership if they left it. A revived member does not see what was sent
+// while they were away (see Participant.CanSee), and starts with it read.
+// It returns gorm.ErrDuplicatedKey when the user is already a member.
+// IsAvatar reports whether a conversation uses the stored file at url as
+// its avatar.
+func (r *ConversationRepositoryImpl) IsAvatar(ctx context.Context, url string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.Conversation{}).Where("avatar = ?", url).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 func (r *ConversationRepositoryImpl) AddParticipant(ctx context.Context, participant *model.Participant) error {
-	return r.db.WithContext(ctx).Create(participant).Error
+	revive := append(clause.AssignmentColumns([]string{"role", "request_status", "invited_by_id", "joined_at", "left_at", "removed_by_id", "updated_at"}),
+		clause.Assignment{Column: clause.Column{Name: "history_gaps"}, Value: gorm.Expr(appendHistoryGap)},
+		clause.Assignment{Column: clause.Column{Name: "last_read_message_id"}, Value: gorm.Expr(lastMessageIDOfConversation)},
+		clause.Assignment{Column: clause.Column{Name: "last_delivered_message_id"}, Value: gorm.Expr(lastMessageIDOfConversation)},
+	)
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "conversation_id"}, {Name: "user_id"}},
+		Where:     clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "participants.left_at IS NOT NULL"}}},
+		DoUpdates: revive,
+	}).Create(participant)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrDuplicatedKey
+	}
+	return nil
+}
+
+// appendHistoryGap adds the absence from leaving until the rejoin to the
+// history gaps of the participant row being upserted.
+const appendHistoryGap = "(COALESCE(participants.history_gaps, '[]')::jsonb || jsonb_build_array(jsonb_build_object('from', participants.left_at, 'until', EXCLUDED.joined_at)))::text"
+
+// lastMessageIDOfConversation is the id of the newest message in the
+// conversation of the participant row being upserted.
+const lastMessageIDOfConversation = "(SELECT COALESCE(MAX(id), 0) FROM messages WHERE messages.conversation_id = EXCLUDED.conversation_id)"
+
+// VisibleToParticipant is the SQL form of Participant.CanSee for messages
+// joined with the participant row under the given alias.
+func VisibleToParticipant(alias string) string {
+	return "(" + alias + ".left_at IS NULL OR messages.created_at <= " + alias + ".left_at)" +
+		" AND NOT EXISTS (SELECT 1 FROM jsonb_array_elements(COALESCE(" + alias + ".history_gaps, '[]')::jsonb) AS gap" +
+		" WHERE messages.created_at > (gap->>'from')::timestamptz AND messages.created_at < (gap->>'until')::timestamptz)"
+}
+
+// RemoveParticipant ends the membership. removedByID is the admin who
+// removed the member, or 0 when they left.
+func (r *ConversationRepositoryImpl) RemoveParticipant(ctx context.Context, participantID int, removedByID int, leftAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.Participant{}).Where("id = ?", participantID).Updates(map[string]interface{}{
+		"left_at":       leftAt,
+		"removed_by_id": removedByID,
+		"pinned_at":     nil,
+		"updated_at":    time.Now(),
+	}).Error
+}
+
+func (r *ConversationRepositoryImpl) GetParticipantByID(ctx context.Context, id int) (*model.Participant, error) {
+	var participant model.Participant
+	if err := r.db.WithContext(ctx).First(&participant, id).Error; err != nil {
+		return nil, err
+	}
+	return &participant, nil
+}
+
+func (r *ConversationRepositoryImpl) GetParticipant(ctx context.Context, conversationID int, userID int) (*model.Participant, error) {
+	var participant model.Participant
+	if err := r.db.WithContext(ctx).Where("conversation_id = ? AND user_id = ?", conversationID, userID).First(&participant).Error; err != nil {
+		return nil, err
+	}
+	return &participant, nil
+}
+
+func (r *ConversationRepositoryImpl) GetParticipantsByConversationID(ctx context.Context, conversationID int) ([]model.Participant, error) {
+	var participants []model.Participant
+	if err := r.db.WithContext(ctx).Where("conversation_id = ?", conversationID).Find(&participants).Error; err != nil {
+		return nil, err
+	}
+	return participants, nil
+}
+
+func (r *ConversationRepositoryImpl) UpdateParticipantRequestStatus(ctx context.Context, participantID int, status string) error {
+	return r.db.WithContext(ctx).Model(&model.Participant{}).Where("id = ?", participantID).Update("request_status", status).Error
+}
+
+func (r *ConversationRepositoryImpl) UpdateParticipantRole(ctx context.Context, participantID int, role string) error {
+	return r.db.WithContext(ctx).Model(&model.Participant{}).Where("id = ?", participantID).Update("role", role).Error
+}
+
+func (r *ConversationRepositoryImpl) UpdateConversation(ctx context.Context, id int, updates map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&model.Conversation{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *ConversationRepositoryImpl) UpdateParticipantSettings(ctx context.Context, participantID int, settings map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&model.Participant{}).Where("id = ?", participantID).Updates(settings).Error
+}
+
+// MarkRead moves the read watermark of the participant up to messageID and
+// clears a manual unread mark. A read message has also been delivered. It
+// reports whether anything changed; watermarks never move back.
+func (r *ConversationRepositoryImpl) MarkRead(ctx context.Context, participantID int, messageID int, readAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.Participant{}).
+		Where("id = ? AND (last_read_message_id < ? OR marked_unread)", participantID, messageID).
+		Updates(map[string]interface{}{
+			"last_read_message_id":      gorm.Expr("GREATEST(last_read_message_id, ?)", messageID),
+			"last_read_at":              readAt,
+			"last_delivered_message_id": gorm.Expr("GREATEST(last_delivered_message_id, ?)", messageID),
+			"marked_unread":             false,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// MarkDelivered moves the delivery watermark of the participant up to
+// messageID and reports whether it moved.
+func (r *ConversationRepositoryImpl) MarkDelivered(ctx context.Context, participantID int, messageID int) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.Participant{}).
+		Where("id = ? AND last_delivered_message_id < ?", participantID, messageID).
+		Update("last_delivered_message_id", messageID)
+	return result.RowsAffected > 0, result.Error
+}
+
+// AdvanceMessageStatus recomputes the watermarks up to which every current
+// member has received and read the conversation and returns how they moved.
+// The sender of a message is always past it, so the minimum over all members
+// is the status of every message below it. Members who have not accepted the
+// conversation are not counted until they do. The stored watermarks never move
+// back, so members joining later do not revert the status of old messages.
+func (r *ConversationRepositoryImpl) AdvanceMessageStatus(ctx context.Context, conversationID int) ([]model.MessageStatusChange, error) {
+	var rows []struct {
+		PreviousDelivered int
+		PreviousRead      int
+		Delivered         int
+		Read              int
+	}
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE conversations
+		SET delivered_message_id = GREATEST(previous.delivered_message_id, watermarks.delivered),
+			read_message_id = GREATEST(previous.read_message_id, watermarks.read)
+		FROM conversations AS previous,
+			(SELECT MIN(last_delivered_message_id) AS delivered, MIN(last_read_message_id) AS read
+			FROM participants WHERE conversation_id = ? AND request_status = ? AND left_at IS NULL AND deleted_at IS NULL) AS watermarks
+		WHERE conversations.id = ? AND previous.id = conversations.id
+			AND (watermarks.delivered > previous.delivered_message_id OR watermarks.read > previous.read_message_id)
+		RETURNING previous.delivered_message_id AS previous_delivered, previous.read_message_id AS previous_read,
+			conversations.delivered_message_id AS delivered, conversations.read_message_id AS read`,
+		conversationID, model.RequestStatusAccepted, conversationID).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []model.MessageStatusChange
+	for _, row := range rows {
+		if row.Delivered > row.PreviousDelivered {
+			changes = append(changes, model.MessageStatusChange{ConversationID: conversationID, Status: model.MessageStatusDelivered, MessageID: row.Delivered})
+		}
+		if row.Read > row.PreviousRead {
+			changes = append(changes, model.MessageStatusChange{ConversationID: conversationID, Status: model.MessageStatusRead, MessageID: row.Read})
+		}
+	}
+	return changes, nil
+}
+
+// GetMessageStatus counts how many current members other than the sender
+// have received and read the message.
+func (r *ConversationRepositoryImpl) GetMessageStatus(ctx context.Context, message *model.Message) (*model.MessageStatus, error) {
+	var status model.MessageStatus
+	err := r.db.WithContext(ctx).Model(&model.Participant{}).
+		Select(`COUNT(*) AS recipient_count,
+			COUNT(*) FILTER (WHERE last_delivered_message_id >= ?) AS delivered_count,
+			COUNT(*) FILTER (WHERE last_read_message_id >= ?) AS read_count`, message.ID, message.ID).
+		Where("conversation_id = ? AND id <> ? AND left_at IS NULL", message.ConversationID, message.ParticipantID).
+		Scan(&status).Error
+	if err != nil {
+		return nil, err
+	}
+
+	status.MessageID = message.ID
+	switch {
+	case status.RecipientCount > 0 && status.ReadCount == status.RecipientCount:
+		status.Status = model.MessageStatusRead
+	case status.RecipientCount > 0 && status.DeliveredCount == status.RecipientCount:
+		status.Status = model.MessageStatusDelivered
+	default:
+		status.Status = model.MessageStatusSent
+	}
+	return &status, nil
+}
+
+// GetUnreadCounts returns the conversations of the user that have unread
+// messages or were marked unread.
+func (r *ConversationRepositoryImpl) GetUnreadCounts(ctx context.Context, userID int) ([]model.UnreadCount, error) {
+	var counts []model.UnreadCount
+	err := r.db.WithContext(ctx).
+		Table("participants").
+		Select(`participants.conversation_id, participants.marked_unread, COUNT(messages.id) AS unread_count`).
+		Joins(`LEFT JOIN messages ON messages.conversation_id = participants.conversation_id
+			AND messages.id > participants.last_read_message_id
+			AND messages.participant_id <> participants.id
+			AND messages.deleted_at IS NULL`).
+		Where("participants.user_id = ? AND participants.request_status = ? AND participants.left_at IS NULL AND participants.deleted_at IS NULL", userID, model.RequestStatusAccepted).
+		Group("participants.conversation_id, participants.marked_unread").
+		Having("COUNT(messages.id) > 0 OR participants.marked_unread").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// GetReaders returns the current members other than the sender whose read
+// watermark has reached the message, earliest reader first.
+func (r *ConversationRepositoryImpl) GetReaders(ctx context.Context, conversationID int, messageID int, senderParticipantID int) ([]model.Participant, error) {
+	var readers []model.Participant
+	err := r.db.WithContext(ctx).
+		Where("conversation_id = ? AND id <> ? AND last_read_message_id >= ? AND left_at IS NULL", conversationID, senderParticipantID, messageID).
+		Order("last_read_at").
+		Find(&readers).Error
+	if err != nil {
+		return nil, err
+	}
+	return readers, nil
+}
+
+// GetSenderUserIDs returns the users who sent the messages of the
+// conversation after afterMessageID up to upToMessageID, leaving out system
+// messages.
+func (r *ConversationRepositoryImpl) GetSenderUserIDs(ctx context.Context, conversationID int, afterMessageID int, upToMessageID int) ([]int, error) {
+	var userIDs []int
+	err := r.db.WithContext(ctx).Model(&model.Message{}).
+		Joins("JOIN participants ON participants.id = messages.participant_id").
+		Where("messages.conversation_id = ? AND messages.id > ? AND messages.id <= ? AND messages.kind <> ?", conversationID, afterMessageID, upToMessageID, model.MessageKindSystem).
+		Distinct().
+		Pluck("participants.user_id", &userIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+// PinConversation pins the conversation above the user's other pins.
+func (r *ConversationRepositoryImpl) PinConversation(ctx context.Context, participantID int, userID int) error {
+	top := r.db.Model(&model.Participant{}).Select("COALESCE(MIN(pin_position), 0) - 1").
+		Where("user_id = ? AND pinned_at IS NOT NULL", userID)
+	return r.db.WithContext(ctx).Model(&model.Participant{}).Where("id = ? AND pinned_at IS NULL", participantID).Updates(map[string]interface{}{
+		"pinned_at":    time.Now(),
+		"pin_position": top,
+	}).Error
+}
+
+func (r *ConversationRepositoryImpl) CountPinnedByUserID(ctx context.Context, userID int) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.Participant{}).Where("user_id = ? AND pinned_at IS NOT NULL AND left_at IS NULL", userID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ReorderPins gives the user's pinned conversations the order of
+// conversationIDs. Conversations that are not pinned are ignored.
+func (r *ConversationRepositoryImpl) ReorderPins(ctx context.Context, userID int, conversationIDs []int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for position, conversationID := range conversationIDs {
+			if err := tx.Model(&model.Participant{}).
+				Where("user_id = ? AND conversation_id = ? AND pinned_at IS NOT NULL", userID, conversationID).
+				Update("pin_position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// TransferOwnership makes the target the owner and the previous owner an admin.
+func (r *ConversationRepositoryImpl) TransferOwnership(ctx context.Context, conversationID int, fromParticipantID int, toParticipantID int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Participant{}).Where("id = ? AND conversation_id = ?", fromParticipantID, conversationID).Update("role", model.RoleAdmin).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Participant{}).Where("id = ? AND conversation_id = ?", toParticipantID, conversationID).Update("role", model.RoleOwner).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Conversation{}).Where("id = ?", conversationID).Update("user_id", tx.Model(&model.Participant{}).Select("user_id").Where("id = ?", toParticipantID)).Error
+	})
+}
+
+func (r *ConversationRepositoryImpl) CountMessagesByParticipantID(ctx context.Context, participantID int) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.Message{}).Where("participant_id = ?", participantID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
 }
 
-func (r *ConversationRepositoryImpl) GetMessagesByConversationID(ctx context.Context, conversationID int) ([]model.Message, error) {
+// GetMessagesByConversationID returns the timeline of the participant's
+// conversation as Participant.CanSee lets them see it.
+func (r *ConversationRepositoryImpl) GetMessagesByConversationID(ctx context.Context, participant *model.Participant) ([]model.Message, error) {
 	var messages []model.Message
-	if err := r.db.WithContext(ctx).Where("conversation_id = ?", conversationID).Find(&messages).Error; err != nil {
+	query := r.db.WithContext(ctx).Preload("Media").Preload("SharedContact").
+		Joins("JOIN participants viewer ON viewer.id = ?", participant.ID).
+		Where("messages.conversation_id = ?", participant.ConversationID).
+		Where(VisibleToParticipant("viewer"))
+	if err := query.Order("messages.id").Find(&messages).Error; err != nil {
 		return nil, err
 	}
 	return messages, nil
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+const uniqueViolation = "23505"
+
+// IsDuplicateKey reports whether err is a Postgres unique constraint
+// violation, or gorm.ErrDuplicatedKey from an upsert that found the row
+// already in the state it would have been written in.
+func IsDuplicateKey(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	var sqlErr interface{ SQLState() string }
+	return errors.As(err, &sqlErr) && sqlErr.SQLState() == uniqueViolation
+}
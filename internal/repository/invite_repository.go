@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/messaging-go-service/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InviteRepository interface {
+	CreateInviteLink(ctx context.Context, link *model.InviteLink) error
+	GetInviteLinkByID(ctx context.Context, id int) (*model.InviteLink, error)
+	GetInviteLinkByToken(ctx context.Context, token string) (*model.InviteLink, error)
+	GetInviteLinksByConversationID(ctx context.Context, conversationID int) ([]model.InviteLink, error)
+	RevokeInviteLink(ctx context.Context, id int) error
+	ClaimInviteUse(ctx context.Context, id int) (bool, error)
+	CreateJoinRequest(ctx context.Context, request *model.JoinRequest) error
+	GetJoinRequest(ctx context.Context, conversationID int, userID int) (*model.JoinRequest, error)
+	GetJoinRequestByID(ctx context.Context, id int) (*model.JoinRequest, error)
+	GetPendingJoinRequests(ctx context.Context, conversationID int) ([]model.JoinRequest, error)
+	UpdateJoinRequestStatus(ctx context.Context, id int, status string, reviewedByID int) error
+}
+
+type InviteRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewInviteRepository(db *gorm.DB) InviteRepository {
+	return &InviteRepositoryImpl{db: db}
+}
+
+func (r *InviteRepositoryImpl) CreateInviteLink(ctx context.Context, link *model.InviteLink) error {
+	return r.db.WithContext(ctx).Create(link).Error
+}
+
+func (r *InviteRepositoryImpl) GetInviteLinkByID(ctx context.Context, id int) (*model.InviteLink, error) {
+	var link model.InviteLink
+	if err := r.db.WithContext(ctx).First(&link, id).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *InviteRepositoryImpl) GetInviteLinkByToken(ctx context.Context, token string) (*model.InviteLink, error) {
+	var link model.InviteLink
+	if err := r.db.WithContext(ctx).Where("token = ?", token).First(&link).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *InviteRepositoryImpl) GetInviteLinksByConversationID(ctx context.Context, conversationID int) ([]model.InviteLink, error) {
+	var links []model.InviteLink
+	if err := r.db.WithContext(ctx).Where("conversation_id = ? AND revoked_at IS NULL", conversationID).Order("created_at DESC").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (r *InviteRepositoryImpl) RevokeInviteLink(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Model(&model.InviteLink{}).Where("id = ?", id).Update("revoked_at", time.Now()).Error
+}
+
+// ClaimInviteUse counts one use of the link if it is still usable. The check
+// and the increment are a single statement so concurrent joins cannot exceed
+// MaxUses.
+func (r *InviteRepositoryImpl) ClaimInviteUse(ctx context.Context, id int) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.InviteLink{}).
+		Where("id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?) AND (max_uses = 0 OR uses < max_uses)", id, time.Now()).
+		Update("uses", gorm.Expr("uses + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CreateJoinRequest files a pending request, reopening an earlier one from
+// the same user.
+func (r *InviteRepositoryImpl) CreateJoinRequest(ctx context.Context, request *model.JoinRequest) error {
+	request.Status = model.JoinRequestStatusPending
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "conversation_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"invite_link_id", "status", "reviewed_by_id", "updated_at"}),
+	}).Create(request).Error
+}
+
+func (r *InviteRepositoryImpl) GetJoinRequest(ctx context.Context, conversationID int, userID int) (*model.JoinRequest, error) {
+	var request model.JoinRequest
+	if err := r.db.WithContext(ctx).Where("conversation_id = ? AND user_id = ?", conversationID, userID).First(&request).Error; err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *InviteRepositoryImpl) GetJoinRequestByID(ctx context.Context, id int) (*model.JoinRequest, error) {
+	var request model.JoinRequest
+	if err := r.db.WithContext(ctx).First(&request, id).Error; err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *InviteRepositoryImpl) GetPendingJoinRequests(ctx context.Context, conversationID int) ([]model.JoinRequest, error) {
+	var requests []model.JoinRequest
+	if err := r.db.WithContext(ctx).Where("conversation_id = ? AND status = ?", conversationID, model.JoinRequestStatusPending).Order("updated_at").Find(&requests).Error; err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+func (r *InviteRepositoryImpl) UpdateJoinRequestStatus(ctx context.Context, id int, status string, reviewedByID int) error {
+	return r.db.WithContext(ctx).Model(&model.JoinRequest{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":         status,
+		"reviewed_by_id": reviewedByID,
+	}).Error
+}
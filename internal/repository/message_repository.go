@@ -2,14 +2,24 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/messaging-go-service/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type MessageRepository interface {
 	CreateMessage(ctx context.Context, message *model.Message) error
-	DeleteMessage(ctx context.Context, id int) error
+	GetMessageByID(ctx context.Context, id int) (*model.Message, error)
+	GetMediaByURL(ctx context.Context, url string) (*model.Media, error)
+	DeleteMessage(ctx context.Context, id int) (bool, error)
+	MarkPlayed(ctx context.Context, play *model.MessagePlay) error
+	GetMessagePlays(ctx context.Context, messageID int) ([]model.MessagePlay, error)
+	StartExpiryTimers(ctx context.Context, conversationID int, readerParticipantID int, messageID int, readAt time.Time) error
+	PurgeExpiredMessages(ctx context.Context, now time.Time, limit int) ([]model.Message, error)
+	SearchMessages(ctx context.Context, userID int, search model.MessageSearch, limit int, offset int) ([]model.MessageSearchResult, int64, error)
 }
 
 type MessageRepositoryImpl struct {
@@ -22,10 +32,263 @@ func NewMessageRepositoryImpl(db *gorm.DB) MessageRepository {
 	}
 }
 
+// ErrRequestMessageLimit is returned by CreateMessage when the sender of an
+// unanswered message request already sent their one message.
+var ErrRequestMessageLimit = errors.New("message request limit reached")
+
+// SlowModeError is returned by CreateMessage when the sender posted less
+// than the slow mode interval ago. RetryAfter is how long they have to wait.
+type SlowModeError struct {
+	RetryAfter time.Duration
+}
+
+func (e *SlowModeError) Error() string {
+	return "slow mode is on"
+}
+
+// CreateMessage stores the message. A member's message is inserted while
+// holding the lock on their participant row, which serializes their sends,
+// and the limits checked beforehand without the lock are checked again so
+// that concurrent sends cannot all pass them.
 func (r *MessageRepositoryImpl) CreateMessage(ctx context.Context, message *model.Message) error {
-	return r.db.WithContext(ctx).Create(message).Error
+	if message.Kind == model.MessageKindSystem {
+		return r.db.WithContext(ctx).Create(message).Error
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var sender struct {
+			Kind            string
+			Role            string
+			SlowModeSeconds int
+		}
+		if err := tx.Raw(`SELECT conversations.kind, participants.role, conversations.slow_mode_seconds FROM participants
+			JOIN conversations ON conversations.id = participants.conversation_id
+			WHERE participants.id = ? FOR UPDATE OF participants`, message.ParticipantID).Scan(&sender).Error; err != nil {
+			return err
+		}
+
+		if sender.Kind == model.ConversationKindDirect {
+			var limited bool
+			if err := tx.Raw(`SELECT EXISTS (SELECT 1 FROM participants recipient
+					WHERE recipient.conversation_id = ? AND recipient.id <> ? AND recipient.request_status = ? AND recipient.deleted_at IS NULL)
+				AND EXISTS (SELECT 1 FROM messages WHERE participant_id = ? AND deleted_at IS NULL)`,
+				message.ConversationID, message.ParticipantID, model.RequestStatusPending, message.ParticipantID).Scan(&limited).Error; err != nil {
+				return err
+			}
+			if limited {
+				return ErrRequestMessageLimit
+			}
+		}
+
+		// Slow mode counts messages the sender has since deleted, and admins
+		// are exempt from it.
+		if sender.SlowModeSeconds > 0 && sender.Role != model.RoleOwner && sender.Role != model.RoleAdmin {
+			var lastSentAt *time.Time
+			if err := tx.Unscoped().Model(&model.Message{}).
+				Where("participant_id = ? AND kind <> ?", message.ParticipantID, model.MessageKindSystem).
+				Select("MAX(created_at)").Row().Scan(&lastSentAt); err != nil {
+				return err
+			}
+			if lastSentAt != nil {
+				if wait := time.Until(lastSentAt.Add(time.Duration(sender.SlowModeSeconds) * time.Second)); wait > 0 {
+					return &SlowModeError{RetryAfter: wait}
+				}
+			}
+		}
+
+		return tx.Create(message).Error
+	})
+}
+
+func (r *MessageRepositoryImpl) GetMessageByID(ctx context.Context, id int) (*model.Message, error) {
+	var message model.Message
+	if err := r.db.WithContext(ctx).Preload("Media").Preload("SharedContact").First(&message, id).Error; err != nil {
+		return nil, err
+	}
+	return &message, nil
 }
 
-func (r *MessageRepositoryImpl) DeleteMessage(ctx context.Context, id int) error {
-	return r.db.WithContext(ctx).Delete(&model.Message{}, id).Error
+func (r *MessageRepositoryImpl) GetMediaByURL(ctx context.Context, url string) (*model.Media, error) {
+	var media model.Media
+	if err := r.db.WithContext(ctx).Where("url = ?", url).First(&media).Error; err != nil {
+		return nil, err
+	}
+	return &media, nil
+}
+
+// DeleteMessage deletes the message for everyone along with its pin. It
+// reports whether the message was pinned.
+func (r *MessageRepositoryImpl) DeleteMessage(ctx context.Context, id int) (bool, error) {
+	unpinned := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("message_id = ?", id).Delete(&model.PinnedMessage{})
+		if result.Error != nil {
+			return result.Error
+		}
+		unpinned = result.RowsAffected > 0
+		if err := tx.Delete(&model.Message{}, id).Error; err != nil {
+			return err
+		}
+		return tx.Exec(repointLastMessage, []int{id}).Error
+	})
+	return unpinned, err
+}
+
+// repointLastMessage points conversations whose last message is one of the
+// given deleted messages at the latest remaining one instead.
+const repointLastMessage = `
+	UPDATE conversations SET
+		last_message_id = (SELECT id FROM messages WHERE conversation_id = conversations.id AND deleted_at IS NULL ORDER BY id DESC LIMIT 1),
+		last_message_at = (SELECT created_at FROM messages WHERE conversation_id = conversations.id AND deleted_at IS NULL ORDER BY id DESC LIMIT 1)
+	WHERE last_message_id IN ?`
+
+func (r *MessageRepositoryImpl) MarkPlayed(ctx context.Context, play *model.MessagePlay) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(play).Error
+}
+
+func (r *MessageRepositoryImpl) GetMessagePlays(ctx context.Context, messageID int) ([]model.MessagePlay, error) {
+	var plays []model.MessagePlay
+	if err := r.db.WithContext(ctx).Where("message_id = ?", messageID).Order("played_at").Find(&plays).Error; err != nil {
+		return nil, err
+	}
+	return plays, nil
+}
+
+// StartExpiryTimers starts the disappearing timers of the messages up to
+// messageID that wait for a first read, unless the reader sent them.
+func (r *MessageRepositoryImpl) StartExpiryTimers(ctx context.Context, conversationID int, readerParticipantID int, messageID int, readAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.Message{}).
+		Where("conversation_id = ? AND id <= ? AND participant_id <> ? AND ttl_seconds > 0 AND expires_at IS NULL", conversationID, messageID, readerParticipantID).
+		Update("expires_at", gorm.Expr("CAST(? AS timestamptz) + ttl_seconds * interval '1 second'", readAt)).Error
+}
+
+// PurgeExpiredMessages permanently deletes up to limit messages whose timer
+// has run out, together with everything that holds their content, and
+// returns them with their media so the files can be removed. Soft deletes
+// would leave the content in the database. Messages another instance is
+// purging at the same time are skipped.
+func (r *MessageRepositoryImpl) PurgeExpiredMessages(ctx context.Context, now time.Time, limit int) ([]model.Message, error) {
+	var messages []model.Message
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw(`
+			SELECT id, conversation_id FROM messages
+			WHERE expires_at <= ?
+			ORDER BY expires_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED`, now, limit).Scan(&messages).Error; err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+
+		ids := make([]int, len(messages))
+		for i, message := range messages {
+			ids[i] = message.ID
+		}
+
+		var medias []model.Media
+		if err := tx.Unscoped().Where("message_id IN ?", ids).Find(&medias).Error; err != nil {
+			return err
+		}
+		for i := range medias {
+			for j := range messages {
+				if messages[j].ID == medias[i].MessageID {
+					messages[j].Media = &medias[i]
+				}
+			}
+		}
+
+		for _, dependent := range []interface{}{
+			&model.Media{},
+			&model.SharedContact{},
+			&model.PinnedMessage{},
+			&model.MessagePlay{},
+			&model.MessageView{},
+			&model.ScheduledMessage{},
+		} {
+			if err := tx.Unscoped().Where("message_id IN ?", ids).Delete(dependent).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Where("id IN ?", ids).Delete(&model.Message{}).Error; err != nil {
+			return err
+		}
+		return tx.Exec(repointLastMessage, ids).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+const (
+	// Messages are indexed without stemming since conversations are in any
+	// language. The column and its index are created by the migration.
+	searchConfig = "simple"
+
+	escapedMessageText = `replace(replace(replace(messages.text, '&', '&amp;'), '<', '&lt;'), '>', '&gt;')`
+	searchHeadline     = `ts_headline('` + searchConfig + `', ` + escapedMessageText + `, websearch_to_tsquery('` + searchConfig + `', ?), 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10')`
+	searchRank         = `ts_rank(messages.search_vector, websearch_to_tsquery('` + searchConfig + `', ?))`
+	linkPattern        = `https?://|www\.`
+)
+
+// SearchMessages finds the messages matching the search in every conversation
+// the user belongs to, best matches first, or newest first when there is no
+// text. Members only find what Participant.CanSee lets them see, and deleted,
+// expired and system messages are never found.
+func (r *MessageRepositoryImpl) SearchMessages(ctx context.Context, userID int, search model.MessageSearch, limit int, offset int) ([]model.MessageSearchResult, int64, error) {
+	query := r.db.WithContext(ctx).Table("messages").
+		Joins("JOIN participants ON participants.conversation_id = messages.conversation_id AND participants.user_id = ? AND participants.request_status <> ? AND participants.deleted_at IS NULL", userID, model.RequestStatusDeclined).
+		Joins("JOIN conversations ON conversations.id = messages.conversation_id AND conversations.deleted_at IS NULL").
+		Joins("JOIN participants AS senders ON senders.id = messages.participant_id").
+		Where("messages.deleted_at IS NULL AND messages.kind <> ?", model.MessageKindSystem).
+		Where(VisibleToParticipant("participants")).
+		Where("(messages.expires_at IS NULL OR messages.expires_at > ?)", time.Now())
+
+	if search.Text != "" {
+		query = query.Where("messages.search_vector @@ websearch_to_tsquery('"+searchConfig+"', ?)", search.Text)
+	}
+	if search.FromUserID != 0 {
+		query = query.Where("senders.user_id = ?", search.FromUserID)
+	}
+	if search.ConversationID != 0 {
+		query = query.Where("messages.conversation_id = ?", search.ConversationID)
+	}
+	if search.Before != nil {
+		query = query.Where("messages.created_at < ?", *search.Before)
+	}
+	if search.After != nil {
+		query = query.Where("messages.created_at >= ?", *search.After)
+	}
+	if search.HasMedia {
+		query = query.Where("EXISTS (SELECT 1 FROM medias WHERE medias.message_id = messages.id AND medias.deleted_at IS NULL)")
+	}
+	if search.HasLink {
+		query = query.Where("messages.text ~* ?", linkPattern)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Session(&gorm.Session{})
+	if search.Text != "" {
+		query = query.
+			Select("messages.id AS message_id, messages.conversation_id, senders.user_id, messages.kind, messages.created_at, "+
+				searchHeadline+" AS snippet, "+searchRank+" AS rank", search.Text, search.Text).
+			Order("rank DESC, messages.id DESC")
+	} else {
+		query = query.
+			Select("messages.id AS message_id, messages.conversation_id, senders.user_id, messages.kind, messages.created_at, " +
+				escapedMessageText + " AS snippet, 0 AS rank").
+			Order("messages.id DESC")
+	}
+
+	var results []model.MessageSearchResult
+	if err := query.Limit(limit).Offset(offset).Scan(&results).Error; err != nil {
+		return nil, 0, err
+	}
+	return results, total, nil
 }
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// statementRecorder stands in for the database and records the statements
+// run through it. It only supports statements that return no rows.
+type statementRecorder struct {
+	statements *[]string
+}
+
+// recordingConnPool is a statementRecorder that begins transactions.
+type recordingConnPool struct {
+	statementRecorder
+}
+
+// recordingTx is a transaction of a recordingConnPool. Unlike the pool it
+// cannot begin a transaction, which is how gorm tells them apart.
+type recordingTx struct {
+	statementRecorder
+}
+
+var errQueryNotSupported = errors.New("queries are not supported")
+
+func (p *statementRecorder) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errQueryNotSupported
+}
+
+func (p *statementRecorder) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	*p.statements = append(*p.statements, strings.Join(strings.Fields(query), " "))
+	return driver.RowsAffected(1), nil
+}
+
+func (p *statementRecorder) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errQueryNotSupported
+}
+
+func (p *statementRecorder) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p *recordingConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	*p.statements = append(*p.statements, "BEGIN")
+	return &recordingTx{p.statementRecorder}, nil
+}
+
+func (tx *recordingTx) Commit() error {
+	*tx.statements = append(*tx.statements, "COMMIT")
+	return nil
+}
+
+func (tx *recordingTx) Rollback() error {
+	*tx.statements = append(*tx.statements, "ROLLBACK")
+	return nil
+}
+
+func TestDeleteMessageRepointsLastMessage(t *testing.T) {
+	var statements []string
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: &recordingConnPool{statementRecorder{statements: &statements}}}), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewMessageRepositoryImpl(db).DeleteMessage(context.Background(), 7); err != nil {
+		t.Fatalf("DeleteMessage() error = %v", err)
+	}
+
+	want := []string{
+		`BEGIN`,
+		`DELETE FROM "pinned_messages"`,
+		`UPDATE "messages" SET "deleted_at"`,
+		`UPDATE conversations SET last_message_id`,
+		`COMMIT`,
+	}
+	if len(statements) != len(want) {
+		t.Fatalf("statements = %q, want %d", statements, len(want))
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(statements[i], prefix) {
+			t.Errorf("statement %d = %q, want it to start with %q", i, statements[i], prefix)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 type NotificationRepository interface {
 	CreateNotification(ctx context.Context, notification *model.Notification) error
+	CreateNotifications(ctx context.Context, notifications []model.Notification) error
 	GetNotificationsByUserID(ctx context.Context, userId int) ([]model.Notification, error)
 }
 
@@ -24,6 +25,13 @@ func (r *NotificationRepositoryImpl) CreateNotification(ctx context.Context, not
 	return r.db.WithContext(ctx).Create(notification).Error
 }
 
+func (r *NotificationRepositoryImpl) CreateNotifications(ctx context.Context, notifications []model.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&notifications).Error
+}
+
 func (r *NotificationRepositoryImpl) GetNotificationsByUserID(ctx context.Context, userId int) ([]model.Notification, error) {
 	var notifications []model.Notification
 	if err := r.db.WithContext(ctx).Where("user_id", userId).Find(&notifications).Error; err != nil {
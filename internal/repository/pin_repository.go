@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/messaging-go-service/internal/model"
+	"gorm.io/gorm"
+)
+
+type PinRepository interface {
+	PinMessage(ctx context.Context, pin *model.PinnedMessage, limit int) error
+	UnpinMessage(ctx context.Context, messageID int) (bool, error)
+	GetPinnedMessages(ctx context.Context, conversationID int) ([]model.PinnedMessage, error)
+}
+
+type PinRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewPinRepository(db *gorm.DB) PinRepository {
+	return &PinRepositoryImpl{db: db}
+}
+
+// ErrPinLimit is returned by PinMessage when the conversation already has as
+// many pins as allowed.
+var ErrPinLimit = errors.New("pinned message limit reached")
+
+// PinMessage stores the pin unless the conversation already has limit pins.
+// The pins are counted while holding the lock on the conversation row, so
+// concurrent pins cannot all pass the limit.
+func (r *PinRepositoryImpl) PinMessage(ctx context.Context, pin *model.PinnedMessage, limit int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT id FROM conversations WHERE id = ? FOR UPDATE", pin.ConversationID).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&model.PinnedMessage{}).Where("conversation_id = ?", pin.ConversationID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= int64(limit) {
+			return ErrPinLimit
+		}
+
+		return tx.Create(pin).Error
+	})
+}
+
+// UnpinMessage reports whether the message was pinned.
+func (r *PinRepositoryImpl) UnpinMessage(ctx context.Context, messageID int) (bool, error) {
+	result := r.db.WithContext(ctx).Where("message_id = ?", messageID).Delete(&model.PinnedMessage{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetPinnedMessages returns the pins of the conversation, most recent first.
+func (r *PinRepositoryImpl) GetPinnedMessages(ctx context.Context, conversationID int) ([]model.PinnedMessage, error) {
+	var pins []model.PinnedMessage
+	err := r.db.WithContext(ctx).
+		Preload("Message").Preload("Message.Media").Preload("Message.SharedContact").
+		Where("conversation_id = ?", conversationID).
+		Order("created_at DESC").
+		Find(&pins).Error
+	if err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
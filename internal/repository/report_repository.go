@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/messaging-go-service/internal/model"
+	"gorm.io/gorm"
+)
+
+type ReportRepository interface {
+	CreateReport(ctx context.Context, report *model.Report) error
+}
+
+type ReportRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewReportRepository(db *gorm.DB) ReportRepository {
+	return &ReportRepositoryImpl{db: db}
+}
+
+func (r *ReportRepositoryImpl) CreateReport(ctx context.Context, report *model.Report) error {
+	return r.db.WithContext(ctx).Create(report).Error
+}
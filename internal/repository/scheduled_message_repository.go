@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/messaging-go-service/internal/model"
+	"gorm.io/gorm"
+)
+
+type ScheduledMessageRepository interface {
+	CreateScheduledMessage(ctx context.Context, message *model.ScheduledMessage) error
+	GetScheduledMessageByID(ctx context.Context, id int) (*model.ScheduledMessage, error)
+	GetPendingByUserID(ctx context.Context, userID int, conversationID int) ([]model.ScheduledMessage, error)
+	UpdatePending(ctx context.Context, id int, userID int, updates map[string]interface{}) (bool, error)
+	ClaimDue(ctx context.Context, now time.Time, limit int) ([]model.ScheduledMessage, error)
+	FailStale(ctx context.Context, claimedBefore time.Time) error
+	FinishScheduledMessage(ctx context.Context, id int, updates map[string]interface{}) error
+}
+
+type ScheduledMessageRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewScheduledMessageRepository(db *gorm.DB) ScheduledMessageRepository {
+	return &ScheduledMessageRepositoryImpl{db: db}
+}
+
+func (r *ScheduledMessageRepositoryImpl) CreateScheduledMessage(ctx context.Context, message *model.ScheduledMessage) error {
+	return r.db.WithContext(ctx).Create(message).Error
+}
+
+func (r *ScheduledMessageRepositoryImpl) GetScheduledMessageByID(ctx context.Context, id int) (*model.ScheduledMessage, error) {
+	var message model.ScheduledMessage
+	if err := r.db.WithContext(ctx).First(&message, id).Error; err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// GetPendingByUserID returns the messages the user has yet to send, soonest
+// first, optionally only those for one conversation.
+func (r *ScheduledMessageRepositoryImpl) GetPendingByUserID(ctx context.Context, userID int, conversationID int) ([]model.ScheduledMessage, error) {
+	query := r.db.WithContext(ctx).Where("user_id = ? AND status = ?", userID, model.ScheduledStatusPending)
+	if conversationID != 0 {
+		query = query.Where("conversation_id = ?", conversationID)
+	}
+
+	var messages []model.ScheduledMessage
+	if err := query.Order("send_at").Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// UpdatePending changes a message of the user that has not been picked up
+// for sending yet and reports whether there was one.
+func (r *ScheduledMessageRepositoryImpl) UpdatePending(ctx context.Context, id int, userID int, updates map[string]interface{}) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.ScheduledMessage{}).
+		Where("id = ? AND user_id = ? AND status = ?", id, userID, model.ScheduledStatusPending).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ClaimDue moves up to limit due messages to sending and returns them. Rows
+// another instance is claiming at the same time are skipped rather than
+// waited for, so every message is claimed by exactly one instance.
+func (r *ScheduledMessageRepositoryImpl) ClaimDue(ctx context.Context, now time.Time, limit int) ([]model.ScheduledMessage, error) {
+	var messages []model.ScheduledMessage
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE scheduled_messages SET status = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM scheduled_messages
+			WHERE status = ? AND send_at <= ? AND deleted_at IS NULL
+			ORDER BY send_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		model.ScheduledStatusSending, now, model.ScheduledStatusPending, now, limit).Scan(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// FailStale gives up on messages claimed by an instance that stopped before
+// finishing them. Whether they were sent is unknown, and sending them again
+// could send them twice.
+func (r *ScheduledMessageRepositoryImpl) FailStale(ctx context.Context, claimedBefore time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.ScheduledMessage{}).
+		Where("status = ? AND updated_at < ?", model.ScheduledStatusSending, claimedBefore).
+		Updates(map[string]interface{}{"status": model.ScheduledStatusFailed, "error": "sending was interrupted"}).Error
+}
+
+func (r *ScheduledMessageRepositoryImpl) FinishScheduledMessage(ctx context.Context, id int, updates map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&model.ScheduledMessage{}).Where("id = ?", id).Updates(updates).Error
+}
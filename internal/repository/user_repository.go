@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/messaging-go-service/internal/model"
 	"gorm.io/gorm"
@@ -10,9 +12,19 @@ import (
 type UserRepository interface {
 	CreateUser(ctx context.Context, user *model.User) error
 	GetUserByID(ctx context.Context, id int) (*model.User, error)
-	GetAllUsers(ctx context.Context) ([]model.User, error)
+	GetUsersByIDs(ctx context.Context, ids []int) ([]model.User, error)
+	GetUsersByEmailHashes(ctx context.Context, viewerID int, hashes []string) ([]model.User, error)
+	GetUsersByUsernames(ctx context.Context, usernames []string) ([]model.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*model.User, error)
+	IsUsernameHeld(ctx context.Context, username string, exceptUserID int, since time.Time) (bool, error)
+	ChangeUsername(ctx context.Context, user *model.User, username string, changedAt time.Time) error
+	GetUsernameHistory(ctx context.Context, userID int) ([]model.UsernameHistory, error)
 	UpdateUser(ctx context.Context, userId int, user *model.User) error
+	UpdateLastSeen(ctx context.Context, userID int, lastSeenAt time.Time) error
+	UpdateLastSeenVisibility(ctx context.Context, userID int, visibility string) error
+	UpdateEmailDiscoverability(ctx context.Context, userID int, discoverability string) error
+	SearchUsers(ctx context.Context, viewerID int, query string, limit int, offset int) ([]model.UserSearchResult, int64, error)
 	DeleteUser(ctx context.Context, id int) error
 }
 
@@ -36,17 +48,88 @@ func (r *UserRepositoryImpl) GetUserByID(ctx context.Context, id int) (*model.Us
 	return &user, nil
 }
 
+// GetUserByEmail and GetUserByUsername ignore case, as do the unique
+// indexes on both columns.
 func (r *UserRepositoryImpl) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
-	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("lower(email) = lower(?)", strings.TrimSpace(email)).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *UserRepositoryImpl) GetAllUsers(ctx context.Context) ([]model.User, error) {
+func (r *UserRepositoryImpl) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
+	var user model.User
+	if err := r.db.WithContext(ctx).Where("lower(username) = lower(?)", username).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// IsUsernameHeld reports whether another user gave up the username since the
+// given time.
+func (r *UserRepositoryImpl) IsUsernameHeld(ctx context.Context, username string, exceptUserID int, since time.Time) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.UsernameHistory{}).
+		Where("lower(username) = lower(?) AND user_id <> ? AND changed_at > ?", username, exceptUserID, since).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ChangeUsername renames the user and records their previous username.
+func (r *UserRepositoryImpl) ChangeUsername(ctx context.Context, user *model.User, username string, changedAt time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		history := model.UsernameHistory{UserID: user.ID, Username: user.Username, ChangedAt: changedAt}
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.User{}).Where("id = ?", user.ID).
+			Updates(map[string]interface{}{"username": username, "username_changed_at": changedAt}).Error
+	})
+}
+
+func (r *UserRepositoryImpl) GetUsernameHistory(ctx context.Context, userID int) ([]model.UsernameHistory, error) {
+	var history []model.UsernameHistory
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("changed_at DESC").Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (r *UserRepositoryImpl) GetUsersByIDs(ctx context.Context, ids []int) ([]model.User, error) {
 	var users []model.User
-	if err := r.db.WithContext(ctx).Find(&users).Error; err != nil {
+	if len(ids) == 0 {
+		return users, nil
+	}
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// GetUsersByEmailHashes returns the users with the given email hashes who
+// let the viewer find them by email.
+func (r *UserRepositoryImpl) GetUsersByEmailHashes(ctx context.Context, viewerID int, hashes []string) ([]model.User, error) {
+	var users []model.User
+	if len(hashes) == 0 {
+		return users, nil
+	}
+	if err := r.db.WithContext(ctx).Where("email_hash IN ?", hashes).Where(emailDiscoverableBy, viewerID).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *UserRepositoryImpl) GetUsersByUsernames(ctx context.Context, usernames []string) ([]model.User, error) {
+	var users []model.User
+	if len(usernames) == 0 {
+		return users, nil
+	}
+	lowered := make([]string, len(usernames))
+	for i, username := range usernames {
+		lowered[i] = strings.ToLower(username)
+	}
+	if err := r.db.WithContext(ctx).Where("lower(username) IN ?", lowered).Find(&users).Error; err != nil {
 		return nil, err
 	}
 	return users, nil
@@ -57,5 +140,65 @@ func (r *UserRepositoryImpl) DeleteUser(ctx context.Context, id int) error {
 }
 
 func (r *UserRepositoryImpl) UpdateUser(ctx context.Context, userId int, user *model.User) error {
+	if user.Email != "" {
+		user.EmailHash = model.HashEmail(user.Email)
+	}
 	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userId).Updates(user).Error
 }
+
+func (r *UserRepositoryImpl) UpdateLastSeen(ctx context.Context, userID int, lastSeenAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Update("last_seen_at", lastSeenAt).Error
+}
+
+func (r *UserRepositoryImpl) UpdateLastSeenVisibility(ctx context.Context, userID int, visibility string) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Update("last_seen_visibility", visibility).Error
+}
+
+func (r *UserRepositoryImpl) UpdateEmailDiscoverability(ctx context.Context, userID int, discoverability string) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Update("email_discoverability", discoverability).Error
+}
+
+// emailDiscoverableBy holds for users who let the viewer given as its
+// argument find them by email: everyone, or the users they saved as contacts.
+const emailDiscoverableBy = `(users.email_discoverability = 'everyone' OR (users.email_discoverability = 'contacts' AND EXISTS (
+	SELECT 1 FROM contacts WHERE contacts.user_id = users.id AND contacts.contact_user_id = ? AND contacts.deleted_at IS NULL)))`
+
+// SearchUsers finds users by a fuzzy, case-insensitive match of their
+// username or the name the viewer saved them under, or by the start of their
+// email address if they let the viewer find them by email. Users on either
+// side of a block with the viewer are left out. The trigram indexes these
+// rely on are created by the migration.
+func (r *UserRepositoryImpl) SearchUsers(ctx context.Context, viewerID int, query string, limit int, offset int) ([]model.UserSearchResult, int64, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	contains := "%" + escapeLike(query) + "%"
+	prefix := escapeLike(query) + "%"
+
+	search := r.db.WithContext(ctx).Table("users").
+		Joins("LEFT JOIN contacts ON contacts.contact_user_id = users.id AND contacts.user_id = ? AND contacts.deleted_at IS NULL", viewerID).
+		Where("users.deleted_at IS NULL AND users.id <> ?", viewerID).
+		Where("NOT EXISTS (SELECT 1 FROM blocks WHERE blocks.deleted_at IS NULL AND ((blocks.user_id = ? AND blocks.blocked_user_id = users.id) OR (blocks.user_id = users.id AND blocks.blocked_user_id = ?)))", viewerID, viewerID).
+		Where(`(lower(users.username) LIKE ? OR lower(users.username) % ?
+			OR lower(contacts.saved_name) LIKE ? OR lower(contacts.saved_name) % ?
+			OR (lower(users.email) LIKE ? AND `+emailDiscoverableBy+`))`,
+			contains, query, contains, query, prefix, viewerID)
+
+	var total int64
+	if err := search.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var results []model.UserSearchResult
+	if err := search.Session(&gorm.Session{}).
+		Select(`users.id, users.username, contacts.saved_name AS display_name, users.profile_picture, users.description AS "desc",
+			GREATEST(
+				similarity(lower(users.username), ?),
+				COALESCE(similarity(lower(contacts.saved_name), ?), 0),
+				CASE WHEN lower(users.email) LIKE ? AND `+emailDiscoverableBy+` THEN 1 ELSE 0 END
+			) AS score`, query, query, prefix, viewerID).
+		Order("score DESC, users.username").
+		Limit(limit).Offset(offset).
+		Scan(&results).Error; err != nil {
+		return nil, 0, err
+	}
+	return results, total, nil
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrChannelNotFound = errors.New("channel not found")
+	ErrChannelPrivate  = errors.New("this channel can only be joined with an invite link")
+	ErrNotSubscribed   = errors.New("you are not subscribed to this channel")
+)
+
+type ChannelService interface {
+	CreateChannel(ctx context.Context, userID int, title string, public bool) (*model.Conversation, error)
+	Subscribe(ctx context.Context, channelID int, userID int) error
+	Unsubscribe(ctx context.Context, channelID int, userID int) error
+	Search(ctx context.Context, query string, limit int, offset int) ([]model.Conversation, int64, error)
+	GetSubscribed(ctx context.Context, userID int, limit int, offset int) ([]model.Conversation, int64, error)
+	GetReadableChannel(ctx context.Context, channelID int, userID int) (*model.Conversation, error)
+	GetPosts(ctx context.Context, channelID int, userID int, limit int, offset int) ([]model.Message, int64, error)
+	Publish(ctx context.Context, channelID int, userID int, text string) (*model.Message, error)
+	RecordViews(ctx context.Context, channelID int, userID int, messageIDs []int) error
+}
+
+type ChannelServiceImpl struct {
+	ConversationRepository repository.ConversationRepository
+	ChannelRepository      repository.ChannelRepository
+	Policy                 Policy
+	MessageService         MessageService
+}
+
+func NewChannelService(conversationRepo repository.ConversationRepository, channelRepo repository.ChannelRepository, policy Policy, messageService MessageService) ChannelService {
+	return &ChannelServiceImpl{
+		ConversationRepository: conversationRepo,
+		ChannelRepository:      channelRepo,
+		Policy:                 policy,
+		MessageService:         messageService,
+	}
+}
+
+// CreateChannel creates a channel owned by the user, who is its first
+// participant. Participants of a channel are its staff; readers subscribe.
+func (s *ChannelServiceImpl) CreateChannel(ctx context.Context, userID int, title string, public bool) (*model.Conversation, error) {
+	channel := model.Conversation{
+		Kind:   model.ConversationKindChannel,
+		Title:  title,
+		UserID: userID,
+		Public: public,
+		Participants: []model.Participant{
+			{
+				UserID:        userID,
+				Role:          model.RoleOwner,
+				RequestStatus: model.RequestStatusAccepted,
+				InvitedByID:   userID,
+			},
+		},
+	}
+	if err := s.ConversationRepository.CreateConversation(ctx, &channel); err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+func (s *ChannelServiceImpl) Subscribe(ctx context.Context, channelID int, userID int) error {
+	channel, err := s.getChannel(ctx, channelID)
+	if err != nil {
+		return err
+	}
+	if !channel.Public {
+		return ErrChannelPrivate
+	}
+	return s.ChannelRepository.Subscribe(ctx, channel.ID, userID)
+}
+
+func (s *ChannelServiceImpl) Unsubscribe(ctx context.Context, channelID int, userID int) error {
+	channel, err := s.getChannel(ctx, channelID)
+	if err != nil {
+		return err
+	}
+	return s.ChannelRepository.Unsubscribe(ctx, channel.ID, userID)
+}
+
+func (s *ChannelServiceImpl) Search(ctx context.Context, query string, limit int, offset int) ([]model.Conversation, int64, error) {
+	return s.ChannelRepository.SearchChannels(ctx, query, limit, offset)
+}
+
+func (s *ChannelServiceImpl) GetSubscribed(ctx context.Context, userID int, limit int, offset int) ([]model.Conversation, int64, error) {
+	return s.ChannelRepository.GetSubscribedChannels(ctx, userID, limit, offset)
+}
+
+// GetReadableChannel returns the channel if the user may read it: anyone may
+// read a public channel, a private one only its subscribers and staff.
+func (s *ChannelServiceImpl) GetReadableChannel(ctx context.Context, channelID int, userID int) (*model.Conversation, error) {
+	channel, err := s.getChannel(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if channel.Public || FindParticipant(channel, userID) != nil {
+		return channel, nil
+	}
+
+	subscribed, err := s.ChannelRepository.IsSubscribed(ctx, channel.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !subscribed {
+		return nil, ErrNotSubscribed
+	}
+	return channel, nil
+}
+
+func (s *ChannelServiceImpl) GetPosts(ctx context.Context, channelID int, userID int, limit int, offset int) ([]model.Message, int64, error) {
+	channel, err := s.GetReadableChannel(ctx, channelID, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.ChannelRepository.GetPosts(ctx, channel.ID, limit, offset)
+}
+
+// Publish posts to the channel. Only its owner and admins may post.
+func (s *ChannelServiceImpl) Publish(ctx context.Context, channelID int, userID int, text string) (*model.Message, error) {
+	channel, err := s.getChannel(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Policy.CanSendMessage(ctx, userID, channel.ID, text); err != nil {
+		return nil, err
+	}
+
+	participant := FindParticipant(channel, userID)
+	message := model.Message{
+		ConversationID: channel.ID,
+		ParticipantID:  participant.ID,
+		Kind:           model.MessageKindText,
+		Text:           text,
+	}
+	if err := s.MessageService.Send(ctx, &message, userID); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (s *ChannelServiceImpl) RecordViews(ctx context.Context, channelID int, userID int, messageIDs []int) error {
+	channel, err := s.GetReadableChannel(ctx, channelID, userID)
+	if err != nil {
+		return err
+	}
+	return s.ChannelRepository.RecordViews(ctx, channel.ID, userID, messageIDs)
+}
+
+func (s *ChannelServiceImpl) getChannel(ctx context.Context, channelID int) (*model.Conversation, error) {
+	channel, err := s.ConversationRepository.GetConversationDetailByID(ctx, channelID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChannelNotFound
+		}
+		return nil, err
+	}
+	if channel.Kind != model.ConversationKindChannel {
+		return nil, ErrChannelNotFound
+	}
+	return channel, nil
+}
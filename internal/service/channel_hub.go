@@ -0,0 +1,157 @@
+package service
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/messaging-go-service/config"
+	"github.com/messaging-go-service/internal/repository"
+	"github.com/messaging-go-service/middleware"
+	httputil "github.com/messaging-go-service/pkg/http"
+)
+
+// channelReaderBuffer is how many payloads may queue for a reader before it is
+// considered too slow and disconnected.
+const channelReaderBuffer = 32
+
+var Channels = NewChannelHub()
+
+// ChannelHub delivers channel posts to connected readers. Unlike Hub it
+// indexes connections by channel, so a post only touches the readers of its
+// channel, and every reader has its own queue and writer goroutine, so a slow
+// reader cannot hold up the others.
+type ChannelHub struct {
+	mu      sync.RWMutex
+	readers map[int]map[*channelReader]bool
+}
+
+type channelReader struct {
+	conn *websocket.Conn
+	send chan interface{}
+	once sync.Once
+}
+
+func NewChannelHub() *ChannelHub {
+	return &ChannelHub{readers: make(map[int]map[*channelReader]bool)}
+}
+
+func (h *ChannelHub) add(channelID int, reader *channelReader) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.readers[channelID] == nil {
+		h.readers[channelID] = make(map[*channelReader]bool)
+	}
+	h.readers[channelID][reader] = true
+}
+
+func (h *ChannelHub) remove(channelID int, reader *channelReader) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.readers[channelID], reader)
+	if len(h.readers[channelID]) == 0 {
+		delete(h.readers, channelID)
+	}
+	reader.close()
+}
+
+// Publish queues the payload for every reader of the channel. It never
+// blocks; readers whose queue is full are dropped and can reconnect to catch
+// up.
+func (h *ChannelHub) Publish(channelID int, payload interface{}) {
+	h.mu.RLock()
+	var slow []*channelReader
+	for reader := range h.readers[channelID] {
+		select {
+		case reader.send <- payload:
+		default:
+			slow = append(slow, reader)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, reader := range slow {
+		h.remove(channelID, reader)
+	}
+}
+
+func (r *channelReader) close() {
+	r.once.Do(func() {
+		close(r.send)
+	})
+}
+
+func (r *channelReader) writeLoop() {
+	for payload := range r.send {
+		if err := r.conn.WriteJSON(payload); err != nil {
+			break
+		}
+	}
+	r.conn.Close()
+}
+
+// Deliver sends a new message to everyone connected to its conversation:
+// members through Hub and, for channels, readers through ChannelHub.
+func Deliver(payload MessagePayload) {
+	RecentHub.Broadcast <- payload
+	Channels.Publish(payload.ConversationID, payload)
+}
+
+// Emit sends an event to the same audience as Deliver.
+func Emit(event Event) {
+	RecentHub.Events <- event
+	Channels.Publish(event.ConversationID, event)
+}
+
+// HandleChannelWebSocket streams the posts of a channel to a reader. The
+// connection is receive-only; posts are made through the REST API.
+func HandleChannelWebSocket(w http.ResponseWriter, r *http.Request) {
+	channelID, err := strconv.Atoi(r.URL.Query().Get("channel_id"))
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid channel id"})
+		return
+	}
+
+	// Browsers cannot set headers on a WebSocket handshake, so the token may also come as a query parameter.
+	tokenString := r.URL.Query().Get("token")
+	if authHeader := r.Header.Get("Authorization"); tokenString == "" && strings.HasPrefix(authHeader, "Bearer ") {
+		tokenString = strings.TrimPrefix(authHeader, "Bearer ")
+	}
+	userID, err := middleware.ParseToken(tokenString)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	db := config.GetDBInstance()
+	conversationRepo := repository.NewConversationRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	policy := NewPolicy(repository.NewBlockRepository(db), conversationRepo, userRepo, repository.NewContactRepository(db))
+	messageService := NewMessageService(repository.NewMessageRepositoryImpl(db), NewNotificationService(repository.NewNotificationRepository(db), userRepo, conversationRepo))
+	channelService := NewChannelService(conversationRepo, repository.NewChannelRepository(db), policy, messageService)
+
+	if _, err := channelService.GetReadableChannel(Ctx, channelID, userID); err != nil {
+		httputil.WriteResponse(w, http.StatusForbidden, map[string]string{"error": "You cannot read this channel"})
+		return
+	}
+
+	conn, err := Upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to upgrade connection"})
+		return
+	}
+
+	reader := &channelReader{conn: conn, send: make(chan interface{}, channelReaderBuffer)}
+	Channels.add(channelID, reader)
+	go reader.writeLoop()
+	defer Channels.remove(channelID, reader)
+
+	// Reading is only needed to notice when the reader goes away.
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			break
+		}
+	}
+}
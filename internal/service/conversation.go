@@ -0,0 +1,585 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrParticipantNotFound is returned when the user acted upon is not a
+	// member of the conversation.
+	ErrParticipantNotFound = errors.New("participant not found")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrAlreadyParticipant  = errors.New("user is already a participant of this conversation")
+	ErrTooManyPins         = fmt.Errorf("you can pin at most %d conversations", maxPinnedConversations)
+)
+
+const maxPinnedConversations = 5
+
+// ParticipantSettings changes a member's own preferences for a conversation.
+// Nil fields are left as they are. Muting without MutedUntil mutes forever.
+type ParticipantSettings struct {
+	Muted      *bool      `json:"muted"`
+	MutedUntil *time.Time `json:"muted_until"`
+	Pinned     *bool      `json:"pinned"`
+	Archived   *bool      `json:"archived"`
+	Unread     *bool      `json:"unread"`
+}
+
+// ConversationInfo changes the details and settings shared by everyone in a
+// group or channel. Nil fields are left as they are.
+type ConversationInfo struct {
+	Title              *string `json:"title"`
+	Description        *string `json:"description"`
+	OnlyAdminsPost     *bool   `json:"only_admins_post"`
+	OnlyAdminsEditInfo *bool   `json:"only_admins_edit_info"`
+	OnlyAdminsPin      *bool   `json:"only_admins_pin"`
+	SlowModeSeconds    *int    `json:"slow_mode_seconds"`
+	MessageTTLSeconds  *int    `json:"message_ttl_seconds"`
+	MessageTTLFromRead *bool   `json:"message_ttl_from_read"`
+}
+
+type ConversationService interface {
+	GetOrCreateDirect(ctx context.Context, userID int, otherUserID int) (*model.Conversation, bool, error)
+	Authorize(ctx context.Context, conversationID int, userID int, action Action) (*model.Conversation, *model.Participant, error)
+	RecordSystemMessage(ctx context.Context, conversation *model.Conversation, actor *model.Participant, text string) error
+	RecordNotice(ctx context.Context, conversation *model.Conversation, actor *model.Participant, format string, userIDs ...int) error
+	ChangeRole(ctx context.Context, conversationID int, actorID int, targetUserID int, role string) error
+	TransferOwnership(ctx context.Context, conversationID int, actorID int, targetUserID int) error
+	AddParticipant(ctx context.Context, conversationID int, actorID int, userID int) (*model.Participant, error)
+	RemoveParticipant(ctx context.Context, conversationID int, actorID int, targetUserID int) error
+	Leave(ctx context.Context, conversationID int, userID int) error
+	UpdateSettings(ctx context.Context, conversationID int, userID int, settings ParticipantSettings) error
+	UpdateInfo(ctx context.Context, conversationID int, actorID int, info ConversationInfo) (*model.Conversation, error)
+	UpdateAvatar(ctx context.Context, conversationID int, actorID int, ext string, data []byte) (*model.Conversation, error)
+}
+
+type ConversationServiceImpl struct {
+	ConversationRepository repository.ConversationRepository
+	UserRepository         repository.UserRepository
+	Policy                 Policy
+	NotificationService    NotificationService
+	MessageService         MessageService
+	MediaStorage           MediaStorage
+}
+
+func NewConversationService(conversationRepo repository.ConversationRepository, userRepo repository.UserRepository, policy Policy, notificationService NotificationService, messageService MessageService, mediaStorage MediaStorage) ConversationService {
+	return &ConversationServiceImpl{
+		ConversationRepository: conversationRepo,
+		UserRepository:         userRepo,
+		Policy:                 policy,
+		NotificationService:    notificationService,
+		MessageService:         messageService,
+		MediaStorage:           mediaStorage,
+	}
+}
+
+// GetOrCreateDirect returns the direct conversation between two users,
+// creating it on first use. It reports whether the conversation was created.
+func (s *ConversationServiceImpl) GetOrCreateDirect(ctx context.Context, userID int, otherUserID int) (*model.Conversation, bool, error) {
+	if err := s.Policy.CanInteract(ctx, userID, otherUserID); err != nil {
+		return nil, false, err
+	}
+
+	requestStatus, err := s.Policy.InitialRequestStatus(ctx, userID, otherUserID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	directKey := model.DirectKey(userID, otherUserID)
+	conversation := model.Conversation{
+		Kind:      model.ConversationKindDirect,
+		UserID:    userID,
+		DirectKey: &directKey,
+		Participants: []model.Participant{
+			{UserID: userID, RequestStatus: model.RequestStatusAccepted, InvitedByID: userID},
+			{UserID: otherUserID, RequestStatus: requestStatus, InvitedByID: userID},
+		},
+	}
+
+	created, err := s.ConversationRepository.GetOrCreateDirectConversation(ctx, &conversation)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if created && requestStatus == model.RequestStatusPending {
+		if err := s.NotificationService.NotifyMessageRequest(ctx, userID, otherUserID); err != nil {
+			log.Println("Failed to notify message request", err)
+		}
+	}
+
+	return &conversation, created, nil
+}
+
+// Authorize loads the conversation and the user's membership of it and checks
+// the membership allows the action.
+func (s *ConversationServiceImpl) Authorize(ctx context.Context, conversationID int, userID int, action Action) (*model.Conversation, *model.Participant, error) {
+	conversation, err := s.ConversationRepository.GetConversationDetailByID(ctx, conversationID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	participant := FindParticipant(conversation, userID)
+	if participant == nil {
+		return nil, nil, ErrNotParticipant
+	}
+	if !Can(conversation, participant, action) {
+		return nil, nil, ErrNotAllowed
+	}
+
+	return conversation, participant, nil
+}
+
+// RecordSystemMessage adds a notice such as a role change to the timeline
+// and delivers it to connected clients like any other message.
+func (s *ConversationServiceImpl) RecordSystemMessage(ctx context.Context, conversation *model.Conversation, actor *model.Participant, text string) error {
+	message := model.Message{
+		ConversationID: conversation.ID,
+		ParticipantID:  actor.ID,
+		Kind:           model.MessageKindSystem,
+		Text:           text,
+	}
+	return s.MessageService.Send(ctx, &message, actor.UserID)
+}
+
+func (s *ConversationServiceImpl) ChangeRole(ctx context.Context, conversationID int, actorID int, targetUserID int, role string) error {
+	conversation, actor, err := s.Authorize(ctx, conversationID, actorID, ActionChangeRole)
+	if err != nil {
+		return err
+	}
+
+	target := FindParticipant(conversation, targetUserID)
+	if target == nil {
+		return ErrParticipantNotFound
+	}
+	if !CanAssignRole(actor, target, role) {
+		return ErrNotAllowed
+	}
+	if target.Role == role {
+		return nil
+	}
+
+	if err := s.ConversationRepository.UpdateParticipantRole(ctx, target.ID, role); err != nil {
+		return err
+	}
+
+	verb := "changed the role of"
+	if rank(role) > rank(target.Role) {
+		verb = "promoted"
+	} else if rank(role) < rank(target.Role) {
+		verb = "demoted"
+	}
+	return s.RecordNotice(ctx, conversation, actor, "%s "+verb+" %s to "+roleName(role), actor.UserID, target.UserID)
+}
+
+func (s *ConversationServiceImpl) TransferOwnership(ctx context.Context, conversationID int, actorID int, targetUserID int) error {
+	conversation, actor, err := s.Authorize(ctx, conversationID, actorID, ActionChangeRole)
+	if err != nil {
+		return err
+	}
+	if actor.Role != model.RoleOwner {
+		return ErrNotAllowed
+	}
+
+	target := FindParticipant(conversation, targetUserID)
+	if target == nil {
+		return ErrParticipantNotFound
+	}
+	if target.ID == actor.ID {
+		return nil
+	}
+
+	if err := s.ConversationRepository.TransferOwnership(ctx, conversation.ID, actor.ID, target.ID); err != nil {
+		return err
+	}
+	return s.RecordNotice(ctx, conversation, actor, "%s transferred ownership to %s", actor.UserID, target.UserID)
+}
+
+// AddParticipant adds a user to a group, or brings back a former member,
+// landing in the requests inbox when the actor is not one of their contacts.
+func (s *ConversationServiceImpl) AddParticipant(ctx context.Context, conversationID int, actorID int, userID int) (*model.Participant, error) {
+	// Direct conversations grant no one ActionAddMember.
+	conversation, actor, err := s.Authorize(ctx, conversationID, actorID, ActionAddMember)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.UserRepository.GetUserByID(ctx, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if FindParticipant(conversation, userID) != nil {
+		return nil, ErrAlreadyParticipant
+	}
+
+	if err := s.Policy.CanAddParticipant(ctx, actorID, userID); err != nil {
+		return nil, err
+	}
+
+	requestStatus, err := s.Policy.InitialRequestStatus(ctx, actorID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	participant := model.Participant{
+		ConversationID: conversationID,
+		UserID:         userID,
+		Role:           model.RoleMember,
+		RequestStatus:  requestStatus,
+		InvitedByID:    actorID,
+	}
+	if err := s.ConversationRepository.AddParticipant(ctx, &participant); err != nil {
+		if repository.IsDuplicateKey(err) {
+			return nil, ErrAlreadyParticipant
+		}
+		return nil, err
+	}
+
+	if requestStatus == model.RequestStatusPending {
+		if err := s.NotificationService.NotifyMessageRequest(ctx, actorID, userID); err != nil {
+			log.Println("Failed to notify message request", err)
+		}
+	}
+
+	if err := s.RecordNotice(ctx, conversation, actor, "%s added %s", actorID, userID); err != nil {
+		log.Println("Failed to record system message", err)
+	}
+
+	return &participant, nil
+}
+
+// RemoveParticipant removes a member who ranks below the actor.
+func (s *ConversationServiceImpl) RemoveParticipant(ctx context.Context, conversationID int, actorID int, targetUserID int) error {
+	conversation, actor, err := s.Authorize(ctx, conversationID, actorID, ActionRemoveMember)
+	if err != nil {
+		return err
+	}
+
+	target := FindParticipant(conversation, targetUserID)
+	if target == nil {
+		return ErrParticipantNotFound
+	}
+	if target.ID == actor.ID || rank(target.Role) >= rank(actor.Role) {
+		return ErrNotAllowed
+	}
+
+	if err := s.ConversationRepository.RemoveParticipant(ctx, target.ID, actor.UserID, time.Now()); err != nil {
+		return err
+	}
+	RecentHub.Evict <- ClientInfo{ConversationID: conversationID, UserID: targetUserID}
+
+	if err := s.RecordNotice(ctx, conversation, actor, "%s removed %s", actorID, targetUserID); err != nil {
+		log.Println("Failed to record system message", err)
+	}
+	return nil
+}
+
+// Leave ends the user's membership of a group. The owner has to hand the
+// group over first unless they are its last member.
+func (s *ConversationServiceImpl) Leave(ctx context.Context, conversationID int, userID int) error {
+	conversation, err := s.ConversationRepository.GetConversationDetailByID(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	participant := FindParticipant(conversation, userID)
+	if participant == nil {
+		return ErrNotParticipant
+	}
+	if conversation.Kind == model.ConversationKindDirect {
+		return ErrNotAllowed
+	}
+	if participant.Role == model.RoleOwner && len(conversation.Participants) > 1 {
+		return ErrOwnerMustTransfer
+	}
+
+	// Recorded first so the notice is part of the history the user keeps.
+	if err := s.RecordNotice(ctx, conversation, participant, "%s left", userID); err != nil {
+		log.Println("Failed to record system message", err)
+	}
+
+	if err := s.ConversationRepository.RemoveParticipant(ctx, participant.ID, 0, time.Now()); err != nil {
+		return err
+	}
+	RecentHub.Evict <- ClientInfo{ConversationID: conversationID, UserID: userID}
+	return nil
+}
+
+func (s *ConversationServiceImpl) UpdateSettings(ctx context.Context, conversationID int, userID int, settings ParticipantSettings) error {
+	conversation, err := s.ConversationRepository.GetConversationDetailByID(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	participant := FindParticipant(conversation, userID)
+	if participant == nil {
+		return ErrNotParticipant
+	}
+
+	updates := make(map[string]interface{})
+	if settings.Muted != nil {
+		switch {
+		case !*settings.Muted:
+			updates["muted_until"] = nil
+		case settings.MutedUntil != nil:
+			updates["muted_until"] = *settings.MutedUntil
+		default:
+			updates["muted_until"] = model.MuteForever
+		}
+	}
+	if settings.Archived != nil {
+		if *settings.Archived {
+			updates["archived_at"] = time.Now()
+		} else {
+			updates["archived_at"] = nil
+		}
+	}
+	if settings.Unread != nil {
+		updates["marked_unread"] = *settings.Unread
+	}
+	if settings.Pinned != nil && !*settings.Pinned {
+		updates["pinned_at"] = nil
+	}
+
+	if len(updates) > 0 {
+		if err := s.ConversationRepository.UpdateParticipantSettings(ctx, participant.ID, updates); err != nil {
+			return err
+		}
+	}
+
+	if settings.Pinned != nil && *settings.Pinned && participant.PinnedAt == nil {
+		pinned, err := s.ConversationRepository.CountPinnedByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if pinned >= maxPinnedConversations {
+			return ErrTooManyPins
+		}
+		return s.ConversationRepository.PinConversation(ctx, participant.ID, userID)
+	}
+	return nil
+}
+
+// UpdateInfo applies the changed fields of info. Every change is announced
+// with a conversation.updated event and a notice in the timeline.
+func (s *ConversationServiceImpl) UpdateInfo(ctx context.Context, conversationID int, actorID int, info ConversationInfo) (*model.Conversation, error) {
+	conversation, err := s.ConversationRepository.GetConversationDetailByID(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	actor := FindParticipant(conversation, actorID)
+	if actor == nil {
+		return nil, ErrNotParticipant
+	}
+	if (info.Title != nil || info.Description != nil) && !Can(conversation, actor, ActionRename) {
+		return nil, ErrNotAllowed
+	}
+	if (info.OnlyAdminsPost != nil || info.OnlyAdminsEditInfo != nil || info.OnlyAdminsPin != nil || info.SlowModeSeconds != nil || info.MessageTTLSeconds != nil ||
+		info.MessageTTLFromRead != nil) &&
+		!Can(conversation, actor, ActionChangeSettings) {
+		return nil, ErrNotAllowed
+	}
+
+	updates := make(map[string]interface{})
+	var notices []string
+
+	if info.Title != nil && *info.Title != conversation.Title {
+		updates["title"] = *info.Title
+		notices = append(notices, fmt.Sprintf("%%s changed the title to \"%s\"", escapeFormat(*info.Title)))
+	}
+	if info.Description != nil && *info.Description != conversation.Description {
+		updates["description"] = *info.Description
+		if *info.Description == "" {
+			notices = append(notices, "%s removed the description")
+		} else {
+			notices = append(notices, "%s changed the description")
+		}
+	}
+	if info.OnlyAdminsPost != nil && *info.OnlyAdminsPost != conversation.OnlyAdminsPost {
+		updates["only_admins_post"] = *info.OnlyAdminsPost
+		if *info.OnlyAdminsPost {
+			notices = append(notices, "%s allowed only admins to send messages")
+		} else {
+			notices = append(notices, "%s allowed all members to send messages")
+		}
+	}
+	if info.OnlyAdminsEditInfo != nil && *info.OnlyAdminsEditInfo != conversation.OnlyAdminsEditInfo {
+		updates["only_admins_edit_info"] = *info.OnlyAdminsEditInfo
+		if *info.OnlyAdminsEditInfo {
+			notices = append(notices, "%s allowed only admins to edit the info")
+		} else {
+			notices = append(notices, "%s allowed all members to edit the info")
+		}
+	}
+	if info.OnlyAdminsPin != nil && *info.OnlyAdminsPin != conversation.OnlyAdminsPin {
+		updates["only_admins_pin"] = *info.OnlyAdminsPin
+		if *info.OnlyAdminsPin {
+			notices = append(notices, "%s allowed only admins to pin messages")
+		} else {
+			notices = append(notices, "%s allowed all members to pin messages")
+		}
+	}
+	if info.SlowModeSeconds != nil && *info.SlowModeSeconds != conversation.SlowModeSeconds {
+		updates["slow_mode_seconds"] = *info.SlowModeSeconds
+		if *info.SlowModeSeconds == 0 {
+			notices = append(notices, "%s turned off slow mode")
+		} else {
+			notices = append(notices, "%s set slow mode to one message every "+describeSeconds(*info.SlowModeSeconds))
+		}
+	}
+	if info.MessageTTLSeconds != nil && *info.MessageTTLSeconds != conversation.MessageTTLSeconds {
+		updates["message_ttl_seconds"] = *info.MessageTTLSeconds
+		if *info.MessageTTLSeconds == 0 {
+			notices = append(notices, "%s turned off disappearing messages")
+		} else {
+			notices = append(notices, "%s set messages to disappear after "+describeSeconds(*info.MessageTTLSeconds))
+		}
+	}
+	if info.MessageTTLFromRead != nil && *info.MessageTTLFromRead != conversation.MessageTTLFromRead {
+		updates["message_ttl_from_read"] = *info.MessageTTLFromRead
+		if *info.MessageTTLFromRead {
+			notices = append(notices, "%s set disappearing messages to start their timer when read")
+		} else {
+			notices = append(notices, "%s set disappearing messages to start their timer when sent")
+		}
+	}
+
+	if len(updates) == 0 {
+		return conversation, nil
+	}
+	if err := s.ConversationRepository.UpdateConversation(ctx, conversationID, updates); err != nil {
+		return nil, err
+	}
+	return s.announceUpdate(ctx, conversation, actor, updates, notices)
+}
+
+// UpdateAvatar replaces the photo of the conversation with the image in data,
+// or removes it when data is nil.
+func (s *ConversationServiceImpl) UpdateAvatar(ctx context.Context, conversationID int, actorID int, ext string, data []byte) (*model.Conversation, error) {
+	conversation, actor, err := s.Authorize(ctx, conversationID, actorID, ActionChangeAvatar)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil && conversation.Avatar == "" {
+		return conversation, nil
+	}
+
+	url := ""
+	notice := "%s removed the photo"
+	if data != nil {
+		if url, err = s.MediaStorage.Save(ext, data); err != nil {
+			return nil, err
+		}
+		notice = "%s changed the photo"
+	}
+
+	if err := s.ConversationRepository.UpdateConversation(ctx, conversationID, map[string]interface{}{"avatar": url}); err != nil {
+		if url != "" {
+			if err := s.MediaStorage.Delete(url); err != nil {
+				log.Println("Failed to delete avatar", err)
+			}
+		}
+		return nil, err
+	}
+	if conversation.Avatar != "" {
+		if err := s.MediaStorage.Delete(conversation.Avatar); err != nil {
+			log.Println("Failed to delete previous avatar", err)
+		}
+	}
+
+	return s.announceUpdate(ctx, conversation, actor, map[string]interface{}{"avatar": url}, []string{notice})
+}
+
+// announceUpdate tells connected clients which fields changed, records a
+// notice for each change and returns the updated conversation.
+func (s *ConversationServiceImpl) announceUpdate(ctx context.Context, conversation *model.Conversation, actor *model.Participant, updates map[string]interface{}, notices []string) (*model.Conversation, error) {
+	Emit(Event{Type: EventConversationUpdated, ConversationID: conversation.ID, Data: updates})
+
+	for _, notice := range notices {
+		if err := s.RecordNotice(ctx, conversation, actor, notice, actor.UserID); err != nil {
+			return nil, err
+		}
+	}
+	return s.ConversationRepository.GetConversationDetailByID(ctx, conversation.ID)
+}
+
+// RecordNotice records a system message, filling format with the usernames
+// of the given users.
+func (s *ConversationServiceImpl) RecordNotice(ctx context.Context, conversation *model.Conversation, actor *model.Participant, format string, userIDs ...int) error {
+	users, err := s.UserRepository.GetUsersByIDs(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+	names := make(map[int]string, len(users))
+	for _, user := range users {
+		names[user.ID] = user.Username
+	}
+
+	args := make([]interface{}, 0, len(userIDs))
+	for _, id := range userIDs {
+		args = append(args, names[id])
+	}
+	return s.RecordSystemMessage(ctx, conversation, actor, fmt.Sprintf(format, args...))
+}
+
+// FindParticipant returns the current membership of the user, if any.
+func FindParticipant(conversation *model.Conversation, userID int) *model.Participant {
+	for i := range conversation.Participants {
+		if conversation.Participants[i].UserID == userID {
+			return &conversation.Participants[i]
+		}
+	}
+	return nil
+}
+
+func rank(role string) int {
+	switch role {
+	case model.RoleOwner:
+		return 3
+	case model.RoleAdmin:
+		return 2
+	case model.RoleMember:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// escapeFormat makes user-provided text safe to embed in a RecordNotice format.
+func escapeFormat(text string) string {
+	return strings.ReplaceAll(text, "%", "%%")
+}
+
+// describeSeconds renders an interval in the largest unit that divides it,
+// such as "30 seconds" or "1 day".
+func describeSeconds(seconds int) string {
+	units := []struct {
+		name string
+		size int
+	}{{"week", 7 * 24 * 3600}, {"day", 24 * 3600}, {"hour", 3600}, {"minute", 60}, {"second", 1}}
+
+	for _, unit := range units {
+		if seconds%unit.size == 0 {
+			if count := seconds / unit.size; count != 1 {
+				return fmt.Sprintf("%d %ss", count, unit.name)
+			}
+			return "1 " + unit.name
+		}
+	}
+	return fmt.Sprintf("%d seconds", seconds)
+}
+
+func roleName(role string) string {
+	return strings.ReplaceAll(role, "_", "-")
+}
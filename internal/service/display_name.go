@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+
+	"github.com/messaging-go-service/internal/repository"
+)
+
+type DisplayProfile struct {
+	Name   string
+	Avatar string
+}
+
+// DisplayNameResolver names users the way a viewer sees them: the name saved
+// in the viewer's contacts when there is one, the username otherwise.
+type DisplayNameResolver interface {
+	Resolve(ctx context.Context, viewerID int, userIDs []int) (map[int]DisplayProfile, error)
+}
+
+type DisplayNameResolverImpl struct {
+	UserRepository    repository.UserRepository
+	ContactRepository repository.ContactRepository
+}
+
+func NewDisplayNameResolver(userRepo repository.UserRepository, contactRepo repository.ContactRepository) DisplayNameResolver {
+	return &DisplayNameResolverImpl{
+		UserRepository:    userRepo,
+		ContactRepository: contactRepo,
+	}
+}
+
+func (s *DisplayNameResolverImpl) Resolve(ctx context.Context, viewerID int, userIDs []int) (map[int]DisplayProfile, error) {
+	profiles := make(map[int]DisplayProfile, len(userIDs))
+
+	users, err := s.UserRepository.GetUsersByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		profiles[user.ID] = DisplayProfile{Name: user.Username, Avatar: user.ProfilePicture}
+	}
+
+	contacts, err := s.ContactRepository.GetContactsByContactUserIDs(ctx, viewerID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, contact := range contacts {
+		profile := profiles[contact.ContactUserID]
+		profile.Name = contact.DisplayName
+		profiles[contact.ContactUserID] = profile
+	}
+
+	return profiles, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/messaging-go-service/config"
+	"github.com/messaging-go-service/internal/repository"
+)
+
+const (
+	EventMessageExpired = "message.expired"
+
+	expirySweepInterval  = 5 * time.Second
+	expirySweepBatchSize = 500
+)
+
+// ExpiryService removes disappearing messages once their timer runs out.
+type ExpiryService interface {
+	SweepExpired(ctx context.Context) error
+}
+
+type ExpiryServiceImpl struct {
+	MessageRepository repository.MessageRepository
+	MediaStorage      MediaStorage
+}
+
+func NewExpiryService(messageRepo repository.MessageRepository, mediaStorage MediaStorage) ExpiryService {
+	return &ExpiryServiceImpl{
+		MessageRepository: messageRepo,
+		MediaStorage:      mediaStorage,
+	}
+}
+
+// SweepExpired purges every expired message, deletes its media files and
+// tells the clients of its conversation to drop it.
+func (s *ExpiryServiceImpl) SweepExpired(ctx context.Context) error {
+	for {
+		messages, err := s.MessageRepository.PurgeExpiredMessages(ctx, time.Now(), expirySweepBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, message := range messages {
+			if message.Media != nil && message.Media.Url != "" {
+				if err := s.MediaStorage.Delete(message.Media.Url); err != nil {
+					log.Println("Failed to delete expired media", err)
+				}
+			}
+			Emit(Event{Type: EventMessageExpired, ConversationID: message.ConversationID, Data: map[string]int{"message_id": message.ID}})
+		}
+
+		if len(messages) < expirySweepBatchSize {
+			return nil
+		}
+	}
+}
+
+// RunExpirySweeper purges expired messages in the background. Every instance
+// runs it; MessageRepository.PurgeExpiredMessages keeps them from purging the
+// same message twice.
+func RunExpirySweeper() {
+	db := config.GetDBInstance()
+	expiryService := NewExpiryService(repository.NewMessageRepositoryImpl(db), NewLocalMediaStorage())
+
+	ticker := time.NewTicker(expirySweepInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := expiryService.SweepExpired(Ctx); err != nil {
+			log.Println("Failed to purge expired messages", err)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+// joinRequestCooldown is how long a user whose join request was declined
+// waits before asking again.
+const joinRequestCooldown = 7 * 24 * time.Hour
+
+var (
+	ErrInviteNotFound      = errors.New("invite link not found")
+	ErrInviteInvalid       = errors.New("this invite link has expired or reached its limit")
+	ErrJoinRequestNotFound = errors.New("join request not found")
+	ErrJoinRequestDeclined = errors.New("your join request was declined recently")
+	ErrRemovedByAdmin      = errors.New("you were removed from this conversation, ask an admin to add you back")
+)
+
+// InvitePreview is what someone opening an invite link sees before joining.
+type InvitePreview struct {
+	ConversationID   int    `json:"conversation_id"`
+	Title            string `json:"title"`
+	MemberCount      int    `json:"member_count"`
+	RequiresApproval bool   `json:"requires_approval"`
+}
+
+type InviteService interface {
+	CreateLink(ctx context.Context, conversationID int, actorID int, expiresIn time.Duration, maxUses int, requiresApproval bool) (*model.InviteLink, error)
+	GetLinks(ctx context.Context, conversationID int, actorID int) ([]model.InviteLink, error)
+	RevokeLink(ctx context.Context, conversationID int, actorID int, linkID int) error
+	Preview(ctx context.Context, token string) (*InvitePreview, error)
+	Join(ctx context.Context, token string, userID int) (*model.Participant, *model.JoinRequest, error)
+	GetJoinRequests(ctx context.Context, conversationID int, actorID int) ([]model.JoinRequest, error)
+	ReviewJoinRequest(ctx context.Context, conversationID int, actorID int, requestID int, approve bool) error
+}
+
+type InviteServiceImpl struct {
+	InviteRepository       repository.InviteRepository
+	ConversationRepository repository.ConversationRepository
+	ChannelRepository      repository.ChannelRepository
+	ConversationService    ConversationService
+}
+
+func NewInviteService(inviteRepo repository.InviteRepository, conversationRepo repository.ConversationRepository, channelRepo repository.ChannelRepository, conversationService ConversationService) InviteService {
+	return &InviteServiceImpl{
+		InviteRepository:       inviteRepo,
+		ConversationRepository: conversationRepo,
+		ChannelRepository:      channelRepo,
+		ConversationService:    conversationService,
+	}
+}
+
+func (s *InviteServiceImpl) CreateLink(ctx context.Context, conversationID int, actorID int, expiresIn time.Duration, maxUses int, requiresApproval bool) (*model.InviteLink, error) {
+	// Direct conversations grant no one ActionManageInvites.
+	if _, _, err := s.ConversationService.Authorize(ctx, conversationID, actorID, ActionManageInvites); err != nil {
+		return nil, err
+	}
+
+	token, err := newInviteToken()
+	if err != nil {
+		return nil, err
+	}
+
+	link := model.InviteLink{
+		ConversationID:   conversationID,
+		CreatedByID:      actorID,
+		Token:            token,
+		MaxUses:          maxUses,
+		RequiresApproval: requiresApproval,
+	}
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(expiresIn)
+		link.ExpiresAt = &expiresAt
+	}
+
+	if err := s.InviteRepository.CreateInviteLink(ctx, &link); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (s *InviteServiceImpl) GetLinks(ctx context.Context, conversationID int, actorID int) ([]model.InviteLink, error) {
+	if _, _, err := s.ConversationService.Authorize(ctx, conversationID, actorID, ActionManageInvites); err != nil {
+		return nil, err
+	}
+	return s.InviteRepository.GetInviteLinksByConversationID(ctx, conversationID)
+}
+
+func (s *InviteServiceImpl) RevokeLink(ctx context.Context, conversationID int, actorID int, linkID int) error {
+	if _, _, err := s.ConversationService.Authorize(ctx, conversationID, actorID, ActionManageInvites); err != nil {
+		return err
+	}
+
+	link, err := s.InviteRepository.GetInviteLinkByID(ctx, linkID)
+	if err != nil || link.ConversationID != conversationID {
+		return ErrInviteNotFound
+	}
+	return s.InviteRepository.RevokeInviteLink(ctx, link.ID)
+}
+
+func (s *InviteServiceImpl) Preview(ctx context.Context, token string) (*InvitePreview, error) {
+	link, conversation, err := s.getUsableLink(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	memberCount := len(conversation.Participants)
+	if conversation.Kind == model.ConversationKindChannel {
+		memberCount = int(conversation.SubscriberCount)
+	}
+
+	return &InvitePreview{
+		ConversationID:   conversation.ID,
+		Title:            conversation.Title,
+		MemberCount:      memberCount,
+		RequiresApproval: link.RequiresApproval,
+	}, nil
+}
+
+// Join adds the user to the conversation behind the link, or files a join
+// request when the link requires approval. For a channel the user becomes a
+// subscriber and neither returned value is set. A join request uses up the
+// link only once it is approved; asking again while a request is pending
+// returns that request, and after a decline the user has to wait for
+// joinRequestCooldown. Users an admin removed can only come back through a
+// request.
+func (s *InviteServiceImpl) Join(ctx context.Context, token string, userID int) (*model.Participant, *model.JoinRequest, error) {
+	link, conversation, err := s.getUsableLink(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if FindParticipant(conversation, userID) != nil {
+		return nil, nil, ErrAlreadyParticipant
+	}
+	if !link.RequiresApproval {
+		former, err := s.ConversationRepository.GetParticipant(ctx, conversation.ID, userID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, err
+		}
+		if former != nil && former.RemovedByID != 0 {
+			return nil, nil, ErrRemovedByAdmin
+		}
+	}
+
+	if link.RequiresApproval {
+		existing, err := s.InviteRepository.GetJoinRequest(ctx, conversation.ID, userID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, err
+		}
+		if existing != nil {
+			switch {
+			case existing.Status == model.JoinRequestStatusPending:
+				return nil, existing, nil
+			case existing.Status == model.JoinRequestStatusDeclined && time.Since(existing.UpdatedAt) < joinRequestCooldown:
+				return nil, nil, ErrJoinRequestDeclined
+			}
+		}
+
+		request := model.JoinRequest{
+			ConversationID: conversation.ID,
+			UserID:         userID,
+			InviteLinkID:   link.ID,
+		}
+		if err := s.InviteRepository.CreateJoinRequest(ctx, &request); err != nil {
+			return nil, nil, err
+		}
+		return nil, &request, nil
+	}
+
+	claimed, err := s.InviteRepository.ClaimInviteUse(ctx, link.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !claimed {
+		return nil, nil, ErrInviteInvalid
+	}
+
+	participant, err := s.admit(ctx, conversation, userID, link.CreatedByID)
+	if err != nil || participant == nil {
+		return nil, nil, err
+	}
+
+	if err := s.ConversationService.RecordNotice(ctx, conversation, participant, "%s joined using an invite link", userID); err != nil {
+		log.Println("Failed to record system message", err)
+	}
+	return participant, nil, nil
+}
+
+func (s *InviteServiceImpl) GetJoinRequests(ctx context.Context, conversationID int, actorID int) ([]model.JoinRequest, error) {
+	if _, _, err := s.ConversationService.Authorize(ctx, conversationID, actorID, ActionManageInvites); err != nil {
+		return nil, err
+	}
+	return s.InviteRepository.GetPendingJoinRequests(ctx, conversationID)
+}
+
+func (s *InviteServiceImpl) ReviewJoinRequest(ctx context.Context, conversationID int, actorID int, requestID int, approve bool) error {
+	conversation, actor, err := s.ConversationService.Authorize(ctx, conversationID, actorID, ActionManageInvites)
+	if err != nil {
+		return err
+	}
+
+	request, err := s.InviteRepository.GetJoinRequestByID(ctx, requestID)
+	if err != nil || request.ConversationID != conversationID || request.Status != model.JoinRequestStatusPending {
+		return ErrJoinRequestNotFound
+	}
+
+	if !approve {
+		return s.InviteRepository.UpdateJoinRequestStatus(ctx, request.ID, model.JoinRequestStatusDeclined, actorID)
+	}
+
+	// The request uses up the link it came through only now, and cannot be
+	// approved once the link is revoked, expired or used up.
+	claimed, err := s.InviteRepository.ClaimInviteUse(ctx, request.InviteLinkID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrInviteInvalid
+	}
+
+	participant, err := s.admit(ctx, conversation, request.UserID, actorID)
+	if err != nil && !errors.Is(err, ErrAlreadyParticipant) {
+		return err
+	}
+	if err := s.InviteRepository.UpdateJoinRequestStatus(ctx, request.ID, model.JoinRequestStatusApproved, actorID); err != nil {
+		return err
+	}
+	if participant == nil {
+		return nil
+	}
+
+	if err := s.ConversationService.RecordNotice(ctx, conversation, actor, "%s approved %s to join", actorID, request.UserID); err != nil {
+		log.Println("Failed to record system message", err)
+	}
+	return nil
+}
+
+func (s *InviteServiceImpl) getUsableLink(ctx context.Context, token string) (*model.InviteLink, *model.Conversation, error) {
+	link, err := s.InviteRepository.GetInviteLinkByToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInviteNotFound
+		}
+		return nil, nil, err
+	}
+	if !link.Usable(time.Now()) {
+		return nil, nil, ErrInviteInvalid
+	}
+
+	conversation, err := s.ConversationRepository.GetConversationDetailByID(ctx, link.ConversationID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInviteNotFound
+		}
+		return nil, nil, err
+	}
+	return link, conversation, nil
+}
+
+// admit makes the user a member who chose to join, so the conversation goes
+// straight to their main inbox. Channels get a subscriber instead and no
+// participant is returned.
+func (s *InviteServiceImpl) admit(ctx context.Context, conversation *model.Conversation, userID int, invitedByID int) (*model.Participant, error) {
+	if conversation.Kind == model.ConversationKindChannel {
+		return nil, s.ChannelRepository.Subscribe(ctx, conversation.ID, userID)
+	}
+
+	participant := model.Participant{
+		ConversationID: conversation.ID,
+		UserID:         userID,
+		Role:           model.RoleMember,
+		RequestStatus:  model.RequestStatusAccepted,
+		InvitedByID:    invitedByID,
+	}
+	if err := s.ConversationRepository.AddParticipant(ctx, &participant); err != nil {
+		if repository.IsDuplicateKey(err) {
+			return nil, ErrAlreadyParticipant
+		}
+		return nil, err
+	}
+	return &participant, nil
+}
+
+func newInviteToken() (string, error) {
+	buf := make([]byte, 18)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
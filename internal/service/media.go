@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/messaging-go-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+var ErrMediaNotFound = errors.New("media not found")
+
+type MediaService interface {
+	Authorize(ctx context.Context, url string, userID int) error
+}
+
+type MediaServiceImpl struct {
+	MessageRepository      repository.MessageRepository
+	ConversationRepository repository.ConversationRepository
+	ChannelService         ChannelService
+}
+
+func NewMediaService(messageRepo repository.MessageRepository, conversationRepo repository.ConversationRepository, channelService ChannelService) MediaService {
+	return &MediaServiceImpl{
+		MessageRepository:      messageRepo,
+		ConversationRepository: conversationRepo,
+		ChannelService:         channelService,
+	}
+}
+
+// Authorize checks that the user may fetch the stored file at url. An
+// attachment is served to active members who can see its message and to
+// readers of the channel it was posted in. Conversation avatars are shown
+// in invite previews and channel search, so any signed-in user gets them.
+func (s *MediaServiceImpl) Authorize(ctx context.Context, url string, userID int) error {
+	media, err := s.MessageRepository.GetMediaByURL(ctx, url)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		avatar, err := s.ConversationRepository.IsAvatar(ctx, url)
+		if err != nil {
+			return err
+		}
+		if !avatar {
+			return ErrMediaNotFound
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	message, err := s.MessageRepository.GetMessageByID(ctx, media.MessageID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrMediaNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	participant, err := s.ConversationRepository.GetParticipant(ctx, message.ConversationID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if _, err := s.ChannelService.GetReadableChannel(ctx, message.ConversationID, userID); err != nil {
+			if errors.Is(err, ErrChannelNotFound) {
+				return ErrNotParticipant
+			}
+			return err
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !participant.Active() || !participant.CanSee(message.CreatedAt) {
+		return ErrNotParticipant
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const MediaURLPrefix = "/media/"
+
+type MediaStorage interface {
+	Save(ext string, data []byte) (string, error)
+	Delete(url string) error
+}
+
+type LocalMediaStorage struct {
+	Dir string
+}
+
+func NewLocalMediaStorage() MediaStorage {
+	return &LocalMediaStorage{Dir: MediaDir()}
+}
+
+func MediaDir() string {
+	if dir := os.Getenv("MEDIA_DIR"); dir != "" {
+		return dir
+	}
+	return "uploads"
+}
+
+func (s *LocalMediaStorage) Save(ext string, data []byte) (string, error) {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return "", err
+	}
+
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return "", err
+	}
+	fileName := hex.EncodeToString(name) + ext
+
+	if err := os.WriteFile(filepath.Join(s.Dir, fileName), data, 0o644); err != nil {
+		return "", err
+	}
+	return MediaURLPrefix + fileName, nil
+}
+
+func (s *LocalMediaStorage) Delete(url string) error {
+	fileName := filepath.Base(strings.TrimPrefix(url, MediaURLPrefix))
+	err := os.Remove(filepath.Join(s.Dir, fileName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
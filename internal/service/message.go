@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/messaging-go-service/internal/dto"
+	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/repository"
+)
+
+// MessageService is the last step of sending any message once the caller has
+// checked it may be sent: it stores the message, delivers it to connected
+// clients and notifies the members who are not connected in the background,
+// so a large group does not hold up the sender.
+type MessageService interface {
+	Send(ctx context.Context, message *model.Message, senderUserID int) error
+}
+
+type MessageServiceImpl struct {
+	MessageRepository   repository.MessageRepository
+	NotificationService NotificationService
+	Limiter             RateLimiter
+}
+
+func NewMessageService(messageRepo repository.MessageRepository, notificationService NotificationService) MessageService {
+	return &MessageServiceImpl{
+		MessageRepository:   messageRepo,
+		NotificationService: notificationService,
+		Limiter:             MessageLimiter,
+	}
+}
+
+func (s *MessageServiceImpl) Send(ctx context.Context, message *model.Message, senderUserID int) error {
+	if err := s.MessageRepository.CreateMessage(ctx, message); err != nil {
+		// Policy.CanSendMessage took a token for the message that was not
+		// sent after all.
+		if message.Kind != model.MessageKindSystem {
+			s.Limiter.Refund(messageRateKey(senderUserID))
+		}
+
+		var slowMode *repository.SlowModeError
+		switch {
+		case errors.Is(err, repository.ErrRequestMessageLimit):
+			return ErrRequestPending
+		case errors.As(err, &slowMode):
+			return &RateLimitError{Reason: slowModeReason, RetryAfter: slowMode.RetryAfter}
+		}
+		return err
+	}
+
+	payload := MessagePayload{
+		MessageID:      message.ID,
+		ConversationID: message.ConversationID,
+		UserID:         senderUserID,
+		Kind:           message.Kind,
+		Text:           message.Text,
+		TTLSeconds:     message.TTLSeconds,
+		ExpiresAt:      message.ExpiresAt,
+	}
+	if message.Media != nil {
+		media := dto.NewMedia(message.Media)
+		payload.Media = &media
+	}
+	if message.SharedContact != nil {
+		contact := dto.NewSharedContact(message.SharedContact)
+		payload.SharedContact = &contact
+	}
+	Deliver(payload)
+
+	if message.Kind != model.MessageKindSystem {
+		connected := RecentHub.ConnectedUsers(message.ConversationID)
+		sent := *message
+		go func() {
+			if err := s.NotificationService.NotifyNewMessage(Ctx, &sent, senderUserID, connected); err != nil {
+				log.Println("Failed to notify new message", err)
+			}
+		}()
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/repository"
+)
+
+type NotificationService interface {
+	NotifyMessageRequest(ctx context.Context, inviterID int, userID int) error
+	NotifyNewMessage(ctx context.Context, message *model.Message, senderUserID int, connected map[int]bool) error
+}
+
+type NotificationServiceImpl struct {
+	NotificationRepository repository.NotificationRepository
+	UserRepository         repository.UserRepository
+	ConversationRepository repository.ConversationRepository
+}
+
+func NewNotificationService(notificationRepo repository.NotificationRepository, userRepo repository.UserRepository, conversationRepo repository.ConversationRepository) NotificationService {
+	return &NotificationServiceImpl{
+		NotificationRepository: notificationRepo,
+		UserRepository:         userRepo,
+		ConversationRepository: conversationRepo,
+	}
+}
+
+func (s *NotificationServiceImpl) NotifyMessageRequest(ctx context.Context, inviterID int, userID int) error {
+	inviter, err := s.UserRepository.GetUserByID(ctx, inviterID)
+	if err != nil {
+		return err
+	}
+
+	notification := model.Notification{
+		UserID:  userID,
+		ActorID: inviterID,
+		Type:    model.NotificationTypeMessageRequest,
+		Message: inviter.Username + " sent you a message request",
+	}
+	return s.NotificationRepository.CreateNotification(ctx, &notification)
+}
+
+// NotifyNewMessage notifies the current members of the conversation about a
+// new message, except its sender, members who muted the conversation,
+// members who have not accepted it yet and the connected members, who got
+// the message over their connection.
+func (s *NotificationServiceImpl) NotifyNewMessage(ctx context.Context, message *model.Message, senderUserID int, connected map[int]bool) error {
+	participants, err := s.ConversationRepository.GetParticipantsByConversationID(ctx, message.ConversationID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var recipients []int
+	for _, participant := range participants {
+		if participant.UserID == senderUserID || connected[participant.UserID] || !participant.Active() ||
+			participant.Muted(now) || participant.RequestStatus != model.RequestStatusAccepted {
+			continue
+		}
+		recipients = append(recipients, participant.UserID)
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	sender, err := s.UserRepository.GetUserByID(ctx, senderUserID)
+	if err != nil {
+		return err
+	}
+
+	text := sender.Username + " " + messageSummary(message)
+	notifications := make([]model.Notification, 0, len(recipients))
+	for _, userID := range recipients {
+		notifications = append(notifications, model.Notification{
+			UserID:  userID,
+			ActorID: senderUserID,
+			Type:    model.NotificationTypeMessage,
+			Message: text,
+		})
+	}
+	return s.NotificationRepository.CreateNotifications(ctx, notifications)
+}
+
+// messageSummary describes the message without its content, which must
+// not outlive a deleted or expired message in the notifications table.
+func messageSummary(message *model.Message) string {
+	if message.TTLSeconds > 0 {
+		return "sent a disappearing message"
+	}
+
+	switch message.Kind {
+	case model.MessageKindVoice:
+		return "sent a voice message"
+	case model.MessageKindContact:
+		return "shared a contact"
+	}
+	return "sent you a message"
+}
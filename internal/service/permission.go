@@ -0,0 +1,115 @@
+package service
+
+import (
+	"github.com/messaging-go-service/internal/model"
+)
+
+type Action string
+
+const (
+	ActionSendMessage          Action = "send_message"
+	ActionAddMember            Action = "add_member"
+	ActionRemoveMember         Action = "remove_member"
+	ActionRename               Action = "rename"
+	ActionChangeAvatar         Action = "change_avatar"
+	ActionDeleteOthersMessages Action = "delete_others_messages"
+	ActionPin                  Action = "pin"
+	ActionChangeRole           Action = "change_role"
+	ActionDeleteConversation   Action = "delete_conversation"
+	ActionManageInvites        Action = "manage_invites"
+	ActionChangeSettings       Action = "change_settings"
+)
+
+// permissions is the baseline of what each group role may do. Conversation
+// settings adjust it in Can.
+var permissions = map[string]map[Action]bool{
+	model.RoleOwner: {
+		ActionSendMessage:          true,
+		ActionAddMember:            true,
+		ActionRemoveMember:         true,
+		ActionRename:               true,
+		ActionChangeAvatar:         true,
+		ActionDeleteOthersMessages: true,
+		ActionPin:                  true,
+		ActionChangeRole:           true,
+		ActionDeleteConversation:   true,
+		ActionManageInvites:        true,
+		ActionChangeSettings:       true,
+	},
+	model.RoleAdmin: {
+		ActionSendMessage:          true,
+		ActionAddMember:            true,
+		ActionRemoveMember:         true,
+		ActionRename:               true,
+		ActionChangeAvatar:         true,
+		ActionDeleteOthersMessages: true,
+		ActionPin:                  true,
+		ActionChangeRole:           true,
+		ActionManageInvites:        true,
+		ActionChangeSettings:       true,
+	},
+	model.RoleMember: {
+		ActionSendMessage: true,
+		ActionAddMember:   true,
+	},
+	model.RoleReadOnly: {},
+}
+
+// directPermissions applies to both members of a direct conversation, which
+// has no roles.
+var directPermissions = map[Action]bool{
+	ActionSendMessage:        true,
+	ActionPin:                true,
+	ActionDeleteConversation: true,
+}
+
+func Can(conversation *model.Conversation, participant *model.Participant, action Action) bool {
+	switch conversation.Kind {
+	case model.ConversationKindDirect:
+		return directPermissions[action]
+	case model.ConversationKindChannel:
+		// Only the owner and admins act on a channel; its readers are
+		// subscribers, not participants.
+		if !isAdmin(participant) {
+			return false
+		}
+	}
+
+	switch action {
+	case ActionSendMessage:
+		if conversation.OnlyAdminsPost && !isAdmin(participant) {
+			return false
+		}
+	case ActionRename, ActionChangeAvatar:
+		if !conversation.OnlyAdminsEditInfo && participant.Role == model.RoleMember {
+			return true
+		}
+	case ActionPin:
+		if !conversation.OnlyAdminsPin && participant.Role == model.RoleMember {
+			return true
+		}
+	}
+	return permissions[participant.Role][action]
+}
+
+func isAdmin(participant *model.Participant) bool {
+	return participant.Role == model.RoleOwner || participant.Role == model.RoleAdmin
+}
+
+func IsValidRole(role string) bool {
+	_, ok := permissions[role]
+	return ok
+}
+
+// CanAssignRole applies the rules on top of ActionChangeRole: only the owner
+// grants or revokes admin, admins manage members and read-only members, and
+// ownership only moves through a transfer.
+func CanAssignRole(actor *model.Participant, target *model.Participant, role string) bool {
+	if actor.ID == target.ID || role == model.RoleOwner || target.Role == model.RoleOwner {
+		return false
+	}
+	if actor.Role == model.RoleOwner {
+		return true
+	}
+	return actor.Role == model.RoleAdmin && target.Role != model.RoleAdmin && role != model.RoleAdmin
+}
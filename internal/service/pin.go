@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/messaging-go-service/internal/dto"
+	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	maxPinnedMessages = 10
+
+	EventMessagePinned   = "message.pinned"
+	EventMessageUnpinned = "message.unpinned"
+)
+
+var (
+	ErrMessageNotFound       = errors.New("message not found")
+	ErrAlreadyPinned         = errors.New("message is already pinned")
+	ErrNotPinned             = errors.New("message is not pinned")
+	ErrTooManyPinnedMessages = fmt.Errorf("a conversation can have at most %d pinned messages", maxPinnedMessages)
+)
+
+type PinService interface {
+	Pin(ctx context.Context, messageID int, userID int) (*model.PinnedMessage, error)
+	Unpin(ctx context.Context, messageID int, userID int) error
+	GetPinned(ctx context.Context, conversationID int, userID int) ([]model.PinnedMessage, error)
+}
+
+type PinServiceImpl struct {
+	PinRepository          repository.PinRepository
+	MessageRepository      repository.MessageRepository
+	ConversationRepository repository.ConversationRepository
+	ConversationService    ConversationService
+	ChannelService         ChannelService
+}
+
+func NewPinService(pinRepo repository.PinRepository, messageRepo repository.MessageRepository, conversationRepo repository.ConversationRepository, conversationService ConversationService, channelService ChannelService) PinService {
+	return &PinServiceImpl{
+		PinRepository:          pinRepo,
+		MessageRepository:      messageRepo,
+		ConversationRepository: conversationRepo,
+		ConversationService:    conversationService,
+		ChannelService:         channelService,
+	}
+}
+
+func (s *PinServiceImpl) Pin(ctx context.Context, messageID int, userID int) (*model.PinnedMessage, error) {
+	message, err := s.getMessage(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.Kind == model.MessageKindSystem {
+		return nil, ErrNotAllowed
+	}
+
+	conversation, actor, err := s.ConversationService.Authorize(ctx, message.ConversationID, userID, ActionPin)
+	if err != nil {
+		return nil, err
+	}
+
+	pin := model.PinnedMessage{MessageID: message.ID, ConversationID: conversation.ID, PinnedByID: userID}
+	if err := s.PinRepository.PinMessage(ctx, &pin, maxPinnedMessages); err != nil {
+		if errors.Is(err, repository.ErrPinLimit) {
+			return nil, ErrTooManyPinnedMessages
+		}
+		if repository.IsDuplicateKey(err) {
+			return nil, ErrAlreadyPinned
+		}
+		return nil, err
+	}
+	pin.Message = message
+
+	Emit(Event{Type: EventMessagePinned, ConversationID: conversation.ID, Data: dto.NewPinnedMessage(&pin)})
+	if err := s.ConversationService.RecordNotice(ctx, conversation, actor, "%s pinned a message", userID); err != nil {
+		return nil, err
+	}
+	return &pin, nil
+}
+
+func (s *PinServiceImpl) Unpin(ctx context.Context, messageID int, userID int) error {
+	message, err := s.getMessage(ctx, messageID)
+	if err != nil {
+		return err
+	}
+
+	conversation, actor, err := s.ConversationService.Authorize(ctx, message.ConversationID, userID, ActionPin)
+	if err != nil {
+		return err
+	}
+
+	unpinned, err := s.PinRepository.UnpinMessage(ctx, message.ID)
+	if err != nil {
+		return err
+	}
+	if !unpinned {
+		return ErrNotPinned
+	}
+
+	Emit(Event{Type: EventMessageUnpinned, ConversationID: conversation.ID, Data: map[string]int{"message_id": message.ID}})
+	return s.ConversationService.RecordNotice(ctx, conversation, actor, "%s unpinned a message", userID)
+}
+
+// GetPinned lists the pins visible to the user: members see them all,
+// former members those sent before they left, and channel readers the pins
+// of channels they can read.
+func (s *PinServiceImpl) GetPinned(ctx context.Context, conversationID int, userID int) ([]model.PinnedMessage, error) {
+	participant, err := s.ConversationRepository.GetParticipant(ctx, conversationID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if _, err := s.ChannelService.GetReadableChannel(ctx, conversationID, userID); err != nil {
+			if errors.Is(err, ErrChannelNotFound) {
+				return nil, ErrNotParticipant
+			}
+			return nil, err
+		}
+		return s.PinRepository.GetPinnedMessages(ctx, conversationID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pins, err := s.PinRepository.GetPinnedMessages(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := pins[:0]
+	for _, pin := range pins {
+		if pin.Message != nil && participant.CanSee(pin.Message.CreatedAt) {
+			visible = append(visible, pin)
+		}
+	}
+	return visible, nil
+}
+
+func (s *PinServiceImpl) getMessage(ctx context.Context, messageID int) (*model.Message, error) {
+	message, err := s.MessageRepository.GetMessageByID(ctx, messageID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMessageNotFound
+	}
+	return message, err
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/repository"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	ErrBlocked            = errors.New("this user is not available")
+	ErrMentionBlocked     = errors.New("you cannot mention this user")
+	ErrRequestPending     = errors.New("you can send only one message until your message request is accepted")
+	ErrRequestDeclined    = errors.New("your message request was declined")
+	ErrRequestNotAccepted = errors.New("accept the message request before replying")
+	ErrNotParticipant     = errors.New("you are not a participant of this conversation")
+	ErrNotAllowed         = errors.New("you are not allowed to do this in this conversation")
+	ErrOwnerMustTransfer  = errors.New("transfer ownership before leaving the conversation")
+	ErrOnlyAdminsPost     = errors.New("only admins can send messages in this conversation")
+	ErrPresenceHidden     = errors.New("this user does not share their presence with you")
+)
+
+// IsPolicyViolation reports whether err is one of the rule violations above
+// rather than an unexpected failure.
+func IsPolicyViolation(err error) bool {
+	var rateLimited *RateLimitError
+	if errors.As(err, &rateLimited) {
+		return true
+	}
+	for _, violation := range []error{ErrBlocked, ErrMentionBlocked, ErrRequestPending, ErrRequestDeclined, ErrRequestNotAccepted, ErrNotParticipant, ErrNotAllowed, ErrOwnerMustTransfer, ErrOnlyAdminsPost, ErrPresenceHidden} {
+		if errors.Is(err, violation) {
+			return true
+		}
+	}
+	return false
+}
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{M}\p{N}_@])@([\p{L}\p{M}\p{N}_]+)`)
+
+// Policy decides whether one user may interact with another. Every entry
+// point that lets users reach each other (REST controllers and the WebSocket
+// handler) goes through it so block rules are enforced in one place. User
+// search applies the same rules in its query.
+type Policy interface {
+	CanInteract(ctx context.Context, actorID int, targetID int) error
+	CanAddParticipant(ctx context.Context, actorID int, userID int) error
+	CanSendMessage(ctx context.Context, senderID int, conversationID int, text string) error
+	InitialRequestStatus(ctx context.Context, inviterID int, userID int) (string, error)
+	CanSeePresence(ctx context.Context, viewerID int, userID int) error
+	PresenceViewers(ctx context.Context, userID int, viewerIDs []int) (map[int]bool, error)
+}
+
+type PolicyImpl struct {
+	BlockRepository        repository.BlockRepository
+	ConversationRepository repository.ConversationRepository
+	UserRepository         repository.UserRepository
+	ContactRepository      repository.ContactRepository
+	Limiter                RateLimiter
+}
+
+func NewPolicy(blockRepo repository.BlockRepository, conversationRepo repository.ConversationRepository, userRepo repository.UserRepository, contactRepo repository.ContactRepository) Policy {
+	return &PolicyImpl{
+		BlockRepository:        blockRepo,
+		ConversationRepository: conversationRepo,
+		UserRepository:         userRepo,
+		ContactRepository:      contactRepo,
+		Limiter:                MessageLimiter,
+	}
+}
+
+// CanInteract fails when either user has blocked the other, which is the
+// rule for anything one-to-one such as starting a direct conversation.
+func (p *PolicyImpl) CanInteract(ctx context.Context, actorID int, targetID int) error {
+	for _, pair := range [][2]int{{targetID, actorID}, {actorID, targetID}} {
+		blocked, err := p.BlockRepository.IsBlocked(ctx, pair[0], pair[1])
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return ErrBlocked
+		}
+	}
+	return nil
+}
+
+// CanAddParticipant fails when the user being added has blocked the actor.
+func (p *PolicyImpl) CanAddParticipant(ctx context.Context, actorID int, userID int) error {
+	blocked, err := p.BlockRepository.IsBlocked(ctx, userID, actorID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrBlocked
+	}
+	return nil
+}
+
+// CanSendMessage requires the sender's role to allow posting. In a direct
+// conversation it fails when either member has blocked the other and limits
+// an unanswered message request to one message. Mentions of users who have
+// blocked the sender fail in any conversation.
+func (p *PolicyImpl) CanSendMessage(ctx context.Context, senderID int, conversationID int, text string) error {
+	conversation, err := p.ConversationRepository.GetConversationDetailByID(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	participants := conversation.Participants
+	senderParticipant := FindParticipant(conversation, senderID)
+	if senderParticipant == nil {
+		return ErrNotParticipant
+	}
+	if !Can(conversation, senderParticipant, ActionSendMessage) {
+		if conversation.OnlyAdminsPost {
+			return ErrOnlyAdminsPost
+		}
+		return ErrNotAllowed
+	}
+
+	if conversation.Kind == model.ConversationKindDirect && len(participants) == 2 {
+		sender, recipient := participants[0], participants[1]
+		if sender.UserID != senderID {
+			sender, recipient = recipient, sender
+		}
+
+		if err := p.CanInteract(ctx, senderID, recipient.UserID); err != nil {
+			return err
+		}
+
+		switch {
+		case sender.RequestStatus == model.RequestStatusPending:
+			return ErrRequestNotAccepted
+		case recipient.RequestStatus == model.RequestStatusDeclined:
+			return ErrRequestDeclined
+		case recipient.RequestStatus == model.RequestStatusPending:
+			// MessageRepository.CreateMessage checks this again under a lock.
+			sent, err := p.ConversationRepository.CountMessagesByParticipantID(ctx, sender.ID)
+			if err != nil {
+				return err
+			}
+			if sent > 0 {
+				return ErrRequestPending
+			}
+		}
+	}
+
+	if err := p.checkMentions(ctx, senderID, text); err != nil {
+		return err
+	}
+	return p.checkSendRate(ctx, conversation, senderParticipant)
+}
+
+// checkMentions fails when the text mentions a user who has blocked the
+// sender.
+func (p *PolicyImpl) checkMentions(ctx context.Context, senderID int, text string) error {
+	usernames := ExtractMentions(text)
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	mentioned, err := p.UserRepository.GetUsersByUsernames(ctx, usernames)
+	if err != nil {
+		return err
+	}
+	userIDs := make([]int, 0, len(mentioned))
+	for _, user := range mentioned {
+		userIDs = append(userIDs, user.ID)
+	}
+
+	blockers, err := p.BlockRepository.GetBlockersOf(ctx, senderID, userIDs)
+	if err != nil {
+		return err
+	}
+	if len(blockers) > 0 {
+		return ErrMentionBlocked
+	}
+	return nil
+}
+
+// checkSendRate applies the slow mode of the conversation, which admins are
+// exempt from, and then takes a token from the sender's global message
+// budget. It runs last so that rejected messages do not use up the budget.
+// MessageRepository.CreateMessage checks slow mode again under a lock, and
+// MessageService.Send gives the token back when the message is not stored.
+func (p *PolicyImpl) checkSendRate(ctx context.Context, conversation *model.Conversation, sender *model.Participant) error {
+	if conversation.SlowModeSeconds > 0 && !isAdmin(sender) {
+		lastSentAt, err := p.ConversationRepository.GetLastMessageAt(ctx, sender.ID)
+		if err != nil {
+			return err
+		}
+		if lastSentAt != nil {
+			wait := time.Until(lastSentAt.Add(time.Duration(conversation.SlowModeSeconds) * time.Second))
+			if wait > 0 {
+				return &RateLimitError{Reason: slowModeReason, RetryAfter: wait}
+			}
+		}
+	}
+
+	if ok, wait := p.Limiter.Allow(messageRateKey(sender.UserID)); !ok {
+		return &RateLimitError{Reason: "you are sending messages too fast", RetryAfter: wait}
+	}
+	return nil
+}
+
+// InitialRequestStatus decides where a conversation lands for a user who is
+// added to it: the main inbox when the inviter is one of their contacts, the
+// requests inbox otherwise.
+func (p *PolicyImpl) InitialRequestStatus(ctx context.Context, inviterID int, userID int) (string, error) {
+	if inviterID == userID {
+		return model.RequestStatusAccepted, nil
+	}
+
+	contacts, err := p.ContactRepository.GetContactsByContactUserIDs(ctx, userID, []int{inviterID})
+	if err != nil {
+		return "", err
+	}
+	if len(contacts) > 0 {
+		return model.RequestStatusAccepted, nil
+	}
+	return model.RequestStatusPending, nil
+}
+
+// CanSeePresence fails when the user has blocked the viewer or does not
+// share their presence with them.
+func (p *PolicyImpl) CanSeePresence(ctx context.Context, viewerID int, userID int) error {
+	if viewerID == userID {
+		return nil
+	}
+
+	blocked, err := p.BlockRepository.IsBlocked(ctx, userID, viewerID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrBlocked
+	}
+
+	user, err := p.UserRepository.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	switch user.LastSeenVisibility {
+	case model.VisibilityNobody:
+		return ErrPresenceHidden
+	case model.VisibilityContacts:
+		contacts, err := p.ContactRepository.GetContactsByContactUserIDs(ctx, userID, []int{viewerID})
+		if err != nil {
+			return err
+		}
+		if len(contacts) == 0 {
+			return ErrPresenceHidden
+		}
+	}
+	return nil
+}
+
+// PresenceViewers is CanSeePresence for many viewers at once, looking up the
+// user, their blocks and their contacts once. It returns the viewers who may
+// see the user's presence.
+func (p *PolicyImpl) PresenceViewers(ctx context.Context, userID int, viewerIDs []int) (map[int]bool, error) {
+	viewers := make(map[int]bool, len(viewerIDs))
+	if len(viewerIDs) == 0 {
+		return viewers, nil
+	}
+
+	user, err := p.UserRepository.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	blocked, err := p.BlockRepository.GetBlockedUserIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var contacts []model.Contact
+	if user.LastSeenVisibility == model.VisibilityContacts {
+		contacts, err = p.ContactRepository.GetContactsByContactUserIDs(ctx, userID, viewerIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, viewerID := range viewerIDs {
+		switch {
+		case viewerID == userID:
+			viewers[viewerID] = true
+		case slices.Contains(blocked, viewerID), user.LastSeenVisibility == model.VisibilityNobody:
+			viewers[viewerID] = false
+		case user.LastSeenVisibility == model.VisibilityContacts:
+			viewers[viewerID] = slices.ContainsFunc(contacts, func(contact model.Contact) bool { return contact.ContactUserID == viewerID })
+		default:
+			viewers[viewerID] = true
+		}
+	}
+	return viewers, nil
+}
+
+// ExtractMentions returns the distinct usernames mentioned as @username in
+// text, in NFKC form like the stored usernames.
+func ExtractMentions(text string) []string {
+	seen := make(map[string]bool)
+	var usernames []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		username := norm.NFKC.String(match[1])
+		if !seen[username] {
+			seen[username] = true
+			usernames = append(usernames, username)
+		}
+	}
+	return usernames
+}
@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/messaging-go-service/config"
+	"github.com/messaging-go-service/internal/dto"
+	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	EventPresenceChanged  = "presence.changed"
+	EventPresenceSnapshot = "presence.snapshot"
+
+	maxPresenceSubscriptions = 200
+)
+
+var Presence = NewPresenceTracker(NewMemoryPresenceStore())
+
+// PresenceStore combines the connections every instance holds for a user
+// into their state. The in-memory store only knows this instance; running
+// several instances needs a store shared through the broker.
+type PresenceStore interface {
+	// Update records how many connections, and how many of them active,
+	// this instance holds for the user and returns their overall state.
+	Update(userID int, connections int, active int) string
+	State(userID int) string
+}
+
+type MemoryPresenceStore struct {
+	mu     sync.RWMutex
+	states map[int]string
+}
+
+func NewMemoryPresenceStore() *MemoryPresenceStore {
+	return &MemoryPresenceStore{states: make(map[int]string)}
+}
+
+func (s *MemoryPresenceStore) Update(userID int, connections int, active int) string {
+	state := model.PresenceOffline
+	switch {
+	case active > 0:
+		state = model.PresenceOnline
+	case connections > 0:
+		state = model.PresenceAway
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state == model.PresenceOffline {
+		delete(s.states, userID)
+	} else {
+		s.states[userID] = state
+	}
+	return state
+}
+
+func (s *MemoryPresenceStore) State(userID int) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if state, ok := s.states[userID]; ok {
+		return state
+	}
+	return model.PresenceOffline
+}
+
+// PresenceTracker follows the hub connections of every user, each of which
+// is active or away, and pushes presence.changed events to the connections
+// subscribed to a user whenever their state changes. Whether the subscribers
+// may still see the user is checked again for every change, all at once,
+// since blocks, contacts and the visibility setting change after
+// subscribing.
+type PresenceTracker struct {
+	mu          sync.Mutex
+	store       PresenceStore
+	connections map[int]map[*websocket.Conn]bool
+	states      map[int]string
+	// subscribers maps a watched user to the connections watching them and
+	// the user of each connection.
+	subscribers   map[int]map[*websocket.Conn]int
+	subscriptions map[*websocket.Conn][]int
+	viewers       func(userID int, viewerIDs []int) map[int]bool
+}
+
+// presenceWatcher is a connection subscribed to a user and the user it
+// belongs to.
+type presenceWatcher struct {
+	conn     *websocket.Conn
+	viewerID int
+}
+
+func NewPresenceTracker(store PresenceStore) *PresenceTracker {
+	return &PresenceTracker{
+		store:         store,
+		connections:   make(map[int]map[*websocket.Conn]bool),
+		states:        make(map[int]string),
+		subscribers:   make(map[int]map[*websocket.Conn]int),
+		subscriptions: make(map[*websocket.Conn][]int),
+		viewers:       presenceViewers,
+	}
+}
+
+// presenceViewers asks the policy which of the viewers may see the user's
+// presence. Errors count as not visible.
+func presenceViewers(userID int, viewerIDs []int) map[int]bool {
+	db := config.GetDBInstance()
+	policy := NewPolicy(repository.NewBlockRepository(db), repository.NewConversationRepository(db), repository.NewUserRepository(db), repository.NewContactRepository(db))
+	viewers, err := policy.PresenceViewers(Ctx, userID, viewerIDs)
+	if err != nil {
+		return nil
+	}
+	return viewers
+}
+
+func (t *PresenceTracker) Connect(userID int, conn *websocket.Conn) {
+	t.SetActive(userID, conn, true)
+}
+
+// SetActive marks a connection active or away, as reported by its client.
+func (t *PresenceTracker) SetActive(userID int, conn *websocket.Conn, active bool) {
+	t.mu.Lock()
+	if t.connections[userID] == nil {
+		t.connections[userID] = make(map[*websocket.Conn]bool)
+	}
+	t.connections[userID][conn] = active
+	presence, watchers := t.update(userID, time.Time{})
+	t.mu.Unlock()
+
+	t.announce(presence, watchers)
+}
+
+// Disconnect forgets the connection and its subscriptions and returns the
+// resulting state of the user; at is when they were last seen if the
+// connection was their last one.
+func (t *PresenceTracker) Disconnect(userID int, conn *websocket.Conn, at time.Time) string {
+	t.mu.Lock()
+	delete(t.connections[userID], conn)
+	if len(t.connections[userID]) == 0 {
+		delete(t.connections, userID)
+	}
+	t.unsubscribe(conn)
+	delete(t.subscriptions, conn)
+	presence, watchers := t.update(userID, at)
+	t.mu.Unlock()
+
+	t.announce(presence, watchers)
+	return t.store.State(userID)
+}
+
+// Subscribe replaces the users whose presence changes are pushed to the
+// connection of the viewer.
+func (t *PresenceTracker) Subscribe(conn *websocket.Conn, viewerID int, userIDs []int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.unsubscribe(conn)
+	for _, userID := range userIDs {
+		if t.subscribers[userID] == nil {
+			t.subscribers[userID] = make(map[*websocket.Conn]int)
+		}
+		t.subscribers[userID][conn] = viewerID
+	}
+	t.subscriptions[conn] = userIDs
+}
+
+// Prune drops the subscriptions to the user of the viewers who may no longer
+// see them, such as after the user changed who sees their presence.
+func (t *PresenceTracker) Prune(userID int) {
+	t.mu.Lock()
+	watchers := t.watchers(userID)
+	t.mu.Unlock()
+
+	viewers := t.viewers(userID, viewerIDs(watchers))
+	for _, watcher := range watchers {
+		if viewers[watcher.viewerID] {
+			continue
+		}
+
+		t.mu.Lock()
+		delete(t.subscribers[userID], watcher.conn)
+		if len(t.subscribers[userID]) == 0 {
+			delete(t.subscribers, userID)
+		}
+		subscriptions := t.subscriptions[watcher.conn]
+		for i, watchedID := range subscriptions {
+			if watchedID == userID {
+				t.subscriptions[watcher.conn] = append(subscriptions[:i:i], subscriptions[i+1:]...)
+				break
+			}
+		}
+		t.mu.Unlock()
+	}
+}
+
+// unsubscribe drops every subscription of the connection. The caller holds
+// the lock.
+func (t *PresenceTracker) unsubscribe(conn *websocket.Conn) {
+	for _, watchedID := range t.subscriptions[conn] {
+		delete(t.subscribers[watchedID], conn)
+		if len(t.subscribers[watchedID]) == 0 {
+			delete(t.subscribers, watchedID)
+		}
+	}
+}
+
+// watchers lists the connections subscribed to the user. The caller holds
+// the lock.
+func (t *PresenceTracker) watchers(userID int) []presenceWatcher {
+	watchers := make([]presenceWatcher, 0, len(t.subscribers[userID]))
+	for conn, viewerID := range t.subscribers[userID] {
+		watchers = append(watchers, presenceWatcher{conn: conn, viewerID: viewerID})
+	}
+	return watchers
+}
+
+// viewerIDs returns the distinct users the watching connections belong to.
+func viewerIDs(watchers []presenceWatcher) []int {
+	var ids []int
+	for _, watcher := range watchers {
+		if !slices.Contains(ids, watcher.viewerID) {
+			ids = append(ids, watcher.viewerID)
+		}
+	}
+	return ids
+}
+
+func (t *PresenceTracker) State(userID int) string {
+	return t.store.State(userID)
+}
+
+// update recomputes the state of the user and, when it changed, returns the
+// presence to announce and the connections to announce it to.
+func (t *PresenceTracker) update(userID int, lastSeenAt time.Time) (*model.Presence, []presenceWatcher) {
+	active := 0
+	for _, isActive := range t.connections[userID] {
+		if isActive {
+			active++
+		}
+	}
+
+	state := t.store.Update(userID, len(t.connections[userID]), active)
+	previous, ok := t.states[userID]
+	if !ok {
+		previous = model.PresenceOffline
+	}
+	if state == previous {
+		return nil, nil
+	}
+	if state == model.PresenceOffline {
+		delete(t.states, userID)
+	} else {
+		t.states[userID] = state
+	}
+
+	presence := model.Presence{UserID: userID, State: state}
+	if state == model.PresenceOffline && !lastSeenAt.IsZero() {
+		presence.LastSeenAt = &lastSeenAt
+	}
+
+	return &presence, t.watchers(userID)
+}
+
+// announce pushes the presence to the watchers who may still see it. It is
+// called without the lock, as checking them queries the database.
+func (t *PresenceTracker) announce(presence *model.Presence, watchers []presenceWatcher) {
+	if presence == nil || len(watchers) == 0 {
+		return
+	}
+	event := Event{Type: EventPresenceChanged, Data: dto.NewPresence(presence)}
+	viewers := t.viewers(presence.UserID, viewerIDs(watchers))
+	for _, watcher := range watchers {
+		if !viewers[watcher.viewerID] {
+			continue
+		}
+		RecentHub.Direct <- DirectPayload{Connection: watcher.conn, Payload: event}
+	}
+}
+
+type PresenceService interface {
+	GetPresence(ctx context.Context, viewerID int, userID int) (*model.Presence, error)
+	Subscribe(ctx context.Context, conn *websocket.Conn, viewerID int, userIDs []int) ([]model.Presence, error)
+	SetVisibility(ctx context.Context, userID int, visibility string) error
+}
+
+type PresenceServiceImpl struct {
+	UserRepository repository.UserRepository
+	Policy         Policy
+}
+
+func NewPresenceService(userRepo repository.UserRepository, policy Policy) PresenceService {
+	return &PresenceServiceImpl{
+		UserRepository: userRepo,
+		Policy:         policy,
+	}
+}
+
+func (s *PresenceServiceImpl) GetPresence(ctx context.Context, viewerID int, userID int) (*model.Presence, error) {
+	if err := s.Policy.CanSeePresence(ctx, viewerID, userID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.UserRepository.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	presence := presenceOf(user)
+	return &presence, nil
+}
+
+// Subscribe pushes the presence changes of the users the viewer may see to
+// the connection and returns their current presence. Users the viewer may
+// not see are left out.
+func (s *PresenceServiceImpl) Subscribe(ctx context.Context, conn *websocket.Conn, viewerID int, userIDs []int) ([]model.Presence, error) {
+	if len(userIDs) > maxPresenceSubscriptions {
+		userIDs = userIDs[:maxPresenceSubscriptions]
+	}
+
+	visible := make([]int, 0, len(userIDs))
+	for _, userID := range userIDs {
+		err := s.Policy.CanSeePresence(ctx, viewerID, userID)
+		if err == nil {
+			visible = append(visible, userID)
+		} else if !IsPolicyViolation(err) && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	users, err := s.UserRepository.GetUsersByIDs(ctx, visible)
+	if err != nil {
+		return nil, err
+	}
+
+	Presence.Subscribe(conn, viewerID, visible)
+
+	presences := make([]model.Presence, 0, len(users))
+	for i := range users {
+		presences = append(presences, presenceOf(&users[i]))
+	}
+	return presences, nil
+}
+
+// SetVisibility changes who sees the user's presence and stops pushing it to
+// the subscribers who no longer do.
+func (s *PresenceServiceImpl) SetVisibility(ctx context.Context, userID int, visibility string) error {
+	if err := s.UserRepository.UpdateLastSeenVisibility(ctx, userID, visibility); err != nil {
+		return err
+	}
+	Presence.Prune(userID)
+	return nil
+}
+
+func presenceOf(user *model.User) model.Presence {
+	presence := model.Presence{UserID: user.ID, State: Presence.State(user.ID)}
+	if presence.State == model.PresenceOffline {
+		presence.LastSeenAt = user.LastSeenAt
+	}
+	return presence
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// A sender may post messageRateBurst messages at once and then
+	// messageRatePerSecond per second across all conversations.
+	messageRateBurst     = 20
+	messageRatePerSecond = 1
+
+	limiterPruneInterval = time.Minute
+
+	slowModeReason = "slow mode is on"
+)
+
+// MessageLimiter is shared by every send path, including the per-connection
+// WebSocket handlers, so a sender cannot get around it by switching paths.
+var MessageLimiter RateLimiter = NewTokenBucketLimiter(messageRatePerSecond, messageRateBurst)
+
+// RateLimitError is returned when a message is sent too soon. RetryAfter is
+// how long the sender has to wait.
+type RateLimitError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s, try again in %d seconds", e.Reason, e.RetryAfterSeconds())
+}
+
+// RetryAfterSeconds rounds RetryAfter up to whole seconds as used by the
+// Retry-After header.
+func (e *RateLimitError) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+type RateLimiter interface {
+	// Allow takes a token for key. When none is left it reports how long
+	// until one is available.
+	Allow(key string) (bool, time.Duration)
+	// Refund gives back a token taken by Allow for an action that did not
+	// happen after all.
+	Refund(key string)
+}
+
+// messageRateKey is the MessageLimiter key of a sender.
+func messageRateKey(userID int) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// TokenBucketLimiter keeps one bucket per key in memory, so the limit
+// applies per instance.
+type TokenBucketLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func NewTokenBucketLimiter(ratePerSecond float64, burst int) *TokenBucketLimiter {
+	return &TokenBucketLimiter{
+		rate:    ratePerSecond,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+func (l *TokenBucketLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*l.rate)
+	bucket.updated = now
+
+	if bucket.tokens < 1 {
+		wait := (1 - bucket.tokens) / l.rate
+		return false, time.Duration(wait * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+func (l *TokenBucketLimiter) Refund(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if bucket, ok := l.buckets[key]; ok {
+		bucket.tokens = math.Min(l.burst, bucket.tokens+1)
+	}
+}
+
+// prune forgets buckets that have refilled completely, which behave the
+// same as a missing bucket.
+func (l *TokenBucketLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < limiterPruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.updated).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucketLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewTokenBucketLimiter(1, 3)
+	limiter.now = func() time.Time { return now }
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		refund  bool
+		key     string
+		allowed bool
+		wait    time.Duration
+	}{
+		{name: "burst 1", key: "a", allowed: true},
+		{name: "burst 2", key: "a", allowed: true},
+		{name: "burst 3", key: "a", allowed: true},
+		{name: "empty", key: "a", allowed: false, wait: time.Second},
+		{name: "other key has its own bucket", key: "b", allowed: true},
+		{name: "partly refilled", advance: 500 * time.Millisecond, key: "a", allowed: false, wait: 500 * time.Millisecond},
+		{name: "refilled", advance: 500 * time.Millisecond, key: "a", allowed: true},
+		{name: "refunded", refund: true, key: "a", allowed: true},
+		{name: "empty again", key: "a", allowed: false, wait: time.Second},
+		{name: "capped at burst", advance: time.Hour, key: "a", allowed: true},
+		{name: "capped at burst 2", key: "a", allowed: true},
+		{name: "capped at burst 3", key: "a", allowed: true},
+		{name: "capped at burst 4", key: "a", allowed: false, wait: time.Second},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		if step.refund {
+			limiter.Refund(step.key)
+		}
+		allowed, wait := limiter.Allow(step.key)
+		if allowed != step.allowed || wait != step.wait {
+			t.Fatalf("%s: Allow() = %v, %v, want %v, %v", step.name, allowed, wait, step.allowed, step.wait)
+		}
+	}
+}
+
+func TestTokenBucketLimiterRefundCapped(t *testing.T) {
+	limiter := NewTokenBucketLimiter(1, 2)
+	limiter.Allow("a")
+	limiter.Refund("a")
+	limiter.Refund("a")
+
+	if tokens := limiter.buckets["a"].tokens; tokens > 2 {
+		t.Errorf("tokens = %v after refunds, want at most the burst of 2", tokens)
+	}
+}
+
+func TestTokenBucketLimiterPrune(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewTokenBucketLimiter(1, 2)
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("a")
+	now = now.Add(limiterPruneInterval)
+	limiter.Allow("b")
+
+	if _, ok := limiter.buckets["a"]; ok {
+		t.Error("refilled bucket was not pruned")
+	}
+	if _, ok := limiter.buckets["b"]; !ok {
+		t.Error("bucket in use was pruned")
+	}
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/messaging-go-service/internal/dto"
+	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	EventReceiptRead   = "receipt.read"
+	EventMessageStatus = "message.status"
+)
+
+type ReceiptService interface {
+	MarkRead(ctx context.Context, conversationID int, userID int, messageID int) (*model.MessageRead, error)
+	MarkDelivered(ctx context.Context, conversationID int, userID int, messageID int) error
+	GetMessageStatus(ctx context.Context, messageID int, userID int) (*model.MessageStatus, error)
+	GetUnreadCounts(ctx context.Context, userID int) ([]model.UnreadCount, error)
+	GetSeenBy(ctx context.Context, messageID int, userID int) ([]model.MessageRead, error)
+}
+
+type ReceiptServiceImpl struct {
+	ConversationRepository repository.ConversationRepository
+	MessageRepository      repository.MessageRepository
+}
+
+func NewReceiptService(conversationRepo repository.ConversationRepository, messageRepo repository.MessageRepository) ReceiptService {
+	return &ReceiptServiceImpl{
+		ConversationRepository: conversationRepo,
+		MessageRepository:      messageRepo,
+	}
+}
+
+// MarkRead marks the conversation read up to the message for the user and
+// tells the connected senders of the newly read messages, so they can show
+// their message as read. Everyone else only learns from the message status
+// once all members have read it.
+func (s *ReceiptServiceImpl) MarkRead(ctx context.Context, conversationID int, userID int, messageID int) (*model.MessageRead, error) {
+	participant, message, err := s.getReceivedMessage(ctx, conversationID, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := model.MessageRead{ConversationID: conversationID, UserID: userID, MessageID: message.ID, ReadAt: time.Now()}
+	changed, err := s.ConversationRepository.MarkRead(ctx, participant.ID, message.ID, receipt.ReadAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Receipts only go to members; channel readers do not see who read a post.
+	if changed {
+		if err := s.MessageRepository.StartExpiryTimers(ctx, conversationID, participant.ID, message.ID, receipt.ReadAt); err != nil {
+			return nil, err
+		}
+		senders, err := s.ConversationRepository.GetSenderUserIDs(ctx, conversationID, participant.LastReadMessageID, message.ID)
+		if err != nil {
+			return nil, err
+		}
+		if senders = readReceiptRecipients(senders, userID); len(senders) > 0 {
+			RecentHub.Events <- Event{Type: EventReceiptRead, ConversationID: conversationID, Data: dto.NewMessageRead(&receipt), UserIDs: senders}
+		}
+		if err := s.publishStatus(ctx, conversationID); err != nil {
+			return nil, err
+		}
+	}
+	return &receipt, nil
+}
+
+// readReceiptRecipients leaves the reader out of the senders of the messages
+// they read.
+func readReceiptRecipients(senders []int, readerID int) []int {
+	recipients := make([]int, 0, len(senders))
+	for _, senderID := range senders {
+		if senderID != readerID {
+			recipients = append(recipients, senderID)
+		}
+	}
+	return recipients
+}
+
+// MarkDelivered acknowledges that a device of the user has received the
+// conversation up to the message, over its connection or through a push
+// notification.
+func (s *ReceiptServiceImpl) MarkDelivered(ctx context.Context, conversationID int, userID int, messageID int) error {
+	participant, message, err := s.getReceivedMessage(ctx, conversationID, userID, messageID)
+	if err != nil {
+		return err
+	}
+
+	changed, err := s.ConversationRepository.MarkDelivered(ctx, participant.ID, message.ID)
+	if err != nil || !changed {
+		return err
+	}
+	return s.publishStatus(ctx, conversationID)
+}
+
+func (s *ReceiptServiceImpl) GetMessageStatus(ctx context.Context, messageID int, userID int) (*model.MessageStatus, error) {
+	message, err := s.getVisibleMessage(ctx, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.ConversationRepository.GetMessageStatus(ctx, message)
+}
+
+// publishStatus tells the senders connected to the conversation when their
+// messages have reached or been read by everyone.
+func (s *ReceiptServiceImpl) publishStatus(ctx context.Context, conversationID int) error {
+	changes, err := s.ConversationRepository.AdvanceMessageStatus(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		RecentHub.Events <- Event{Type: EventMessageStatus, ConversationID: conversationID, Data: dto.NewMessageStatusChange(&change)}
+	}
+	return nil
+}
+
+// getReceivedMessage returns the membership of the user and the message they
+// acknowledge, which must belong to the conversation.
+func (s *ReceiptServiceImpl) getReceivedMessage(ctx context.Context, conversationID int, userID int, messageID int) (*model.Participant, *model.Message, error) {
+	participant, err := s.ConversationRepository.GetParticipant(ctx, conversationID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrNotParticipant
+		}
+		return nil, nil, err
+	}
+	if !participant.Active() {
+		return nil, nil, ErrNotParticipant
+	}
+
+	message, err := s.MessageRepository.GetMessageByID(ctx, messageID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && message.ConversationID != conversationID) {
+		return nil, nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return participant, message, nil
+}
+
+func (s *ReceiptServiceImpl) GetUnreadCounts(ctx context.Context, userID int) ([]model.UnreadCount, error) {
+	return s.ConversationRepository.GetUnreadCounts(ctx, userID)
+}
+
+// GetSeenBy lists the members who have read the message. Reads are tracked
+// by watermark, so ReadAt is when the reader last moved their watermark,
+// which is no earlier than when they read this message.
+func (s *ReceiptServiceImpl) GetSeenBy(ctx context.Context, messageID int, userID int) ([]model.MessageRead, error) {
+	message, err := s.getVisibleMessage(ctx, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	readers, err := s.ConversationRepository.GetReaders(ctx, message.ConversationID, message.ID, message.ParticipantID)
+	if err != nil {
+		return nil, err
+	}
+
+	receipts := make([]model.MessageRead, 0, len(readers))
+	for _, reader := range readers {
+		readAt := reader.JoinedAt
+		if reader.LastReadAt != nil {
+			readAt = *reader.LastReadAt
+		}
+		receipts = append(receipts, model.MessageRead{
+			ConversationID: message.ConversationID,
+			UserID:         reader.UserID,
+			MessageID:      message.ID,
+			ReadAt:         readAt,
+		})
+	}
+	return receipts, nil
+}
+
+// getVisibleMessage returns the message if the user is or was a member of
+// its conversation when it was sent.
+func (s *ReceiptServiceImpl) getVisibleMessage(ctx context.Context, messageID int, userID int) (*model.Message, error) {
+	message, err := s.MessageRepository.GetMessageByID(ctx, messageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	participant, err := s.ConversationRepository.GetParticipant(ctx, message.ConversationID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotParticipant
+		}
+		return nil, err
+	}
+	if !participant.CanSee(message.CreatedAt) {
+		return nil, ErrMessageNotFound
+	}
+	return message, nil
+}
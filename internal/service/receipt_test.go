@@ -0,0 +1,57 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestReadReceiptRecipients(t *testing.T) {
+	// User 1 reads messages from users 2 and 3; user 4 sent nothing new and
+	// user 1 also has a second device connected.
+	reader, readerOtherDevice := &websocket.Conn{}, &websocket.Conn{}
+	sender, otherSender, bystander := &websocket.Conn{}, &websocket.Conn{}, &websocket.Conn{}
+	otherConversation := &websocket.Conn{}
+
+	hub := NewHub()
+	for _, client := range []ClientInfo{
+		{Connection: reader, ConversationID: 1, UserID: 1},
+		{Connection: readerOtherDevice, ConversationID: 1, UserID: 1},
+		{Connection: sender, ConversationID: 1, UserID: 2},
+		{Connection: otherSender, ConversationID: 1, UserID: 3},
+		{Connection: bystander, ConversationID: 1, UserID: 4},
+		{Connection: otherConversation, ConversationID: 2, UserID: 2},
+	} {
+		hub.Clients[client.Connection] = client
+	}
+
+	tests := []struct {
+		name    string
+		senders []int
+		want    []*websocket.Conn
+	}{
+		{name: "senders of the read messages", senders: []int{1, 2, 3}, want: []*websocket.Conn{sender, otherSender}},
+		{name: "one sender", senders: []int{2}, want: []*websocket.Conn{sender}},
+		{name: "only own messages", senders: []int{1}},
+		{name: "no messages", senders: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recipients := readReceiptRecipients(tt.senders, 1)
+			var got []*websocket.Conn
+			if len(recipients) > 0 {
+				got = hub.recipients(1, 0, recipients)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d connections, want %d", len(got), len(tt.want))
+			}
+			for _, conn := range tt.want {
+				if !slices.Contains(got, conn) {
+					t.Errorf("connection of user %d did not get the receipt", hub.Clients[conn].UserID)
+				}
+			}
+		})
+	}
+}
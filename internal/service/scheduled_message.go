@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/messaging-go-service/config"
+	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	schedulerInterval  = 5 * time.Second
+	schedulerBatchSize = 100
+	// A claimed message still sending after schedulerClaimTimeout belongs
+	// to an instance that stopped.
+	schedulerClaimTimeout = 10 * time.Minute
+)
+
+var ErrScheduledMessageNotFound = errors.New("scheduled message not found or already sent")
+
+type ScheduledMessageService interface {
+	Schedule(ctx context.Context, conversationID int, userID int, text string, sendAt time.Time) (*model.ScheduledMessage, error)
+	GetPending(ctx context.Context, userID int, conversationID int) ([]model.ScheduledMessage, error)
+	Update(ctx context.Context, id int, userID int, text *string, sendAt *time.Time) (*model.ScheduledMessage, error)
+	Cancel(ctx context.Context, id int, userID int) error
+	SendDue(ctx context.Context) error
+}
+
+type ScheduledMessageServiceImpl struct {
+	ScheduledMessageRepository repository.ScheduledMessageRepository
+	ConversationRepository     repository.ConversationRepository
+	Policy                     Policy
+	ConversationService        ConversationService
+	MessageService             MessageService
+}
+
+func NewScheduledMessageService(scheduledMessageRepo repository.ScheduledMessageRepository, conversationRepo repository.ConversationRepository, policy Policy, conversationService ConversationService, messageService MessageService) ScheduledMessageService {
+	return &ScheduledMessageServiceImpl{
+		ScheduledMessageRepository: scheduledMessageRepo,
+		ConversationRepository:     conversationRepo,
+		Policy:                     policy,
+		ConversationService:        conversationService,
+		MessageService:             messageService,
+	}
+}
+
+// Schedule only checks that the user may post to the conversation; the full
+// send checks run again when the message is due.
+func (s *ScheduledMessageServiceImpl) Schedule(ctx context.Context, conversationID int, userID int, text string, sendAt time.Time) (*model.ScheduledMessage, error) {
+	if _, _, err := s.ConversationService.Authorize(ctx, conversationID, userID, ActionSendMessage); err != nil {
+		return nil, err
+	}
+
+	message := model.ScheduledMessage{
+		ConversationID: conversationID,
+		UserID:         userID,
+		Text:           text,
+		SendAt:         sendAt,
+		Status:         model.ScheduledStatusPending,
+	}
+	if err := s.ScheduledMessageRepository.CreateScheduledMessage(ctx, &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (s *ScheduledMessageServiceImpl) GetPending(ctx context.Context, userID int, conversationID int) ([]model.ScheduledMessage, error) {
+	return s.ScheduledMessageRepository.GetPendingByUserID(ctx, userID, conversationID)
+}
+
+func (s *ScheduledMessageServiceImpl) Update(ctx context.Context, id int, userID int, text *string, sendAt *time.Time) (*model.ScheduledMessage, error) {
+	updates := make(map[string]interface{})
+	if text != nil {
+		updates["text"] = *text
+	}
+	if sendAt != nil {
+		updates["send_at"] = *sendAt
+	}
+
+	if len(updates) > 0 {
+		updated, err := s.ScheduledMessageRepository.UpdatePending(ctx, id, userID, updates)
+		if err != nil {
+			return nil, err
+		}
+		if !updated {
+			return nil, ErrScheduledMessageNotFound
+		}
+	}
+
+	message, err := s.ScheduledMessageRepository.GetScheduledMessageByID(ctx, id)
+	if err != nil || message.UserID != userID {
+		return nil, ErrScheduledMessageNotFound
+	}
+	return message, nil
+}
+
+func (s *ScheduledMessageServiceImpl) Cancel(ctx context.Context, id int, userID int) error {
+	cancelled, err := s.ScheduledMessageRepository.UpdatePending(ctx, id, userID, map[string]interface{}{"status": model.ScheduledStatusCancelled})
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return ErrScheduledMessageNotFound
+	}
+	return nil
+}
+
+// SendDue sends the messages that are due through the same checks and
+// pipeline as a message sent right away. A message held back by the rate
+// limit or slow mode is put off until the sender may post again; one the
+// sender may no longer send is marked failed with the reason.
+func (s *ScheduledMessageServiceImpl) SendDue(ctx context.Context) error {
+	now := time.Now()
+	if err := s.ScheduledMessageRepository.FailStale(ctx, now.Add(-schedulerClaimTimeout)); err != nil {
+		return err
+	}
+
+	due, err := s.ScheduledMessageRepository.ClaimDue(ctx, now, schedulerBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, scheduled := range due {
+		updates := map[string]interface{}{"status": model.ScheduledStatusSent}
+		message, err := s.send(ctx, scheduled)
+		var rateLimited *RateLimitError
+		switch {
+		case errors.As(err, &rateLimited):
+			updates = map[string]interface{}{"status": model.ScheduledStatusPending, "send_at": time.Now().Add(rateLimited.RetryAfter)}
+		case err != nil:
+			updates = map[string]interface{}{"status": model.ScheduledStatusFailed, "error": clientError(err, "Failed to send message")}
+		default:
+			updates["message_id"] = message.ID
+		}
+
+		if err := s.ScheduledMessageRepository.FinishScheduledMessage(ctx, scheduled.ID, updates); err != nil {
+			log.Println("Failed to update scheduled message", err)
+		}
+	}
+	return nil
+}
+
+func (s *ScheduledMessageServiceImpl) send(ctx context.Context, scheduled model.ScheduledMessage) (*model.Message, error) {
+	if err := s.Policy.CanSendMessage(ctx, scheduled.UserID, scheduled.ConversationID, scheduled.Text); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotParticipant
+		}
+		return nil, err
+	}
+
+	participant, err := s.ConversationRepository.GetParticipant(ctx, scheduled.ConversationID, scheduled.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	message := model.Message{
+		ConversationID: scheduled.ConversationID,
+		ParticipantID:  participant.ID,
+		Kind:           model.MessageKindText,
+		Text:           scheduled.Text,
+	}
+	if err := s.MessageService.Send(ctx, &message, scheduled.UserID); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// RunScheduler sends scheduled messages as they become due. Every instance
+// runs it; ScheduledMessageRepository.ClaimDue keeps them from sending the
+// same message twice.
+func RunScheduler() {
+	db := config.GetDBInstance()
+	conversationRepo := repository.NewConversationRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	policy := NewPolicy(repository.NewBlockRepository(db), conversationRepo, userRepo, repository.NewContactRepository(db))
+	notificationService := NewNotificationService(repository.NewNotificationRepository(db), userRepo, conversationRepo)
+	messageService := NewMessageService(repository.NewMessageRepositoryImpl(db), notificationService)
+	conversationService := NewConversationService(conversationRepo, userRepo, policy, notificationService, messageService, NewLocalMediaStorage())
+	scheduledMessageService := NewScheduledMessageService(repository.NewScheduledMessageRepository(db), conversationRepo, policy, conversationService, messageService)
+
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := scheduledMessageService.SendDue(Ctx); err != nil {
+			log.Println("Failed to send scheduled messages", err)
+		}
+	}
+}
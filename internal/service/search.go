@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/repository"
+)
+
+const searchDateLayout = "2006-01-02"
+
+var (
+	ErrInvalidSearch = errors.New("invalid search")
+	ErrEmptySearch   = fmt.Errorf("%w: the search is empty", ErrInvalidSearch)
+)
+
+type SearchService interface {
+	SearchMessages(ctx context.Context, userID int, query string, limit int, offset int) ([]model.MessageSearchResult, int64, error)
+}
+
+type SearchServiceImpl struct {
+	MessageRepository repository.MessageRepository
+	UserRepository    repository.UserRepository
+}
+
+func NewSearchService(messageRepo repository.MessageRepository, userRepo repository.UserRepository) SearchService {
+	return &SearchServiceImpl{
+		MessageRepository: messageRepo,
+		UserRepository:    userRepo,
+	}
+}
+
+// SearchMessages searches the messages the user can see. Besides words, the
+// query takes the filters from:<username|me>, in:<conversation id>,
+// before:<date>, after:<date>, has:media and has:link, where dates are
+// YYYY-MM-DD and before and after exclude the day itself.
+func (s *SearchServiceImpl) SearchMessages(ctx context.Context, userID int, query string, limit int, offset int) ([]model.MessageSearchResult, int64, error) {
+	search, fromUsername, err := parseMessageSearch(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch fromUsername {
+	case "":
+	case "me":
+		search.FromUserID = userID
+	default:
+		users, err := s.UserRepository.GetUsersByUsernames(ctx, []string{fromUsername})
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(users) == 0 {
+			return []model.MessageSearchResult{}, 0, nil
+		}
+		search.FromUserID = users[0].ID
+	}
+
+	return s.MessageRepository.SearchMessages(ctx, userID, *search, limit, offset)
+}
+
+// parseMessageSearch splits the filters from the words of the query. The
+// sender is returned as a username for the caller to resolve.
+func parseMessageSearch(query string) (*model.MessageSearch, string, error) {
+	var search model.MessageSearch
+	var fromUsername string
+	var words []string
+	filtered := false
+
+	for _, token := range strings.Fields(query) {
+		key, value, ok := strings.Cut(token, ":")
+		if !ok || value == "" {
+			words = append(words, token)
+			continue
+		}
+
+		switch strings.ToLower(key) {
+		case "from":
+			fromUsername = strings.TrimPrefix(value, "@")
+		case "in":
+			conversationID, err := strconv.Atoi(value)
+			if err != nil || conversationID <= 0 {
+				return nil, "", fmt.Errorf("%w: in: takes a conversation id", ErrInvalidSearch)
+			}
+			search.ConversationID = conversationID
+		case "before", "after":
+			day, err := time.Parse(searchDateLayout, value)
+			if err != nil {
+				return nil, "", fmt.Errorf("%w: %s: takes a date as YYYY-MM-DD", ErrInvalidSearch, key)
+			}
+			if strings.ToLower(key) == "before" {
+				search.Before = &day
+			} else {
+				next := day.AddDate(0, 0, 1)
+				search.After = &next
+			}
+		case "has":
+			switch strings.ToLower(value) {
+			case "media":
+				search.HasMedia = true
+			case "link":
+				search.HasLink = true
+			default:
+				return nil, "", fmt.Errorf("%w: has: takes media or link", ErrInvalidSearch)
+			}
+		default:
+			words = append(words, token)
+			continue
+		}
+		filtered = true
+	}
+
+	search.Text = strings.Join(words, " ")
+	if search.Text == "" && !filtered {
+		return nil, "", ErrEmptySearch
+	}
+	return &search, fromUsername, nil
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/messaging-go-service/internal/model"
+)
+
+func TestParseMessageSearch(t *testing.T) {
+	day := func(value string) *time.Time {
+		parsed, _ := time.Parse(searchDateLayout, value)
+		return &parsed
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    model.MessageSearch
+		from    string
+		wantErr error
+	}{
+		{name: "words only", query: "  hello   world ", want: model.MessageSearch{Text: "hello world"}},
+		{name: "from username", query: "from:@alice lunch", want: model.MessageSearch{Text: "lunch"}, from: "alice"},
+		{name: "from me", query: "from:me", from: "me"},
+		{name: "in conversation", query: "in:42 report", want: model.MessageSearch{Text: "report", ConversationID: 42}},
+		{name: "before excludes the day", query: "before:2024-03-10", want: model.MessageSearch{Before: day("2024-03-10")}},
+		{name: "after excludes the day", query: "after:2024-03-10", want: model.MessageSearch{After: day("2024-03-11")}},
+		{name: "after at the end of a month", query: "after:2024-02-29", want: model.MessageSearch{After: day("2024-03-01")}},
+		{name: "has media and link", query: "HAS:Media has:link", want: model.MessageSearch{HasMedia: true, HasLink: true}},
+		{name: "filter keys ignore case", query: "IN:7 x", want: model.MessageSearch{Text: "x", ConversationID: 7}},
+		{name: "unknown key is a word", query: "http://example.com note:", want: model.MessageSearch{Text: "http://example.com note:"}},
+		{name: "empty value is a word", query: "from: hi", want: model.MessageSearch{Text: "from: hi"}},
+		{name: "empty", query: "   ", wantErr: ErrEmptySearch},
+		{name: "bad conversation id", query: "in:abc", wantErr: ErrInvalidSearch},
+		{name: "negative conversation id", query: "in:-1", wantErr: ErrInvalidSearch},
+		{name: "bad date", query: "before:10/03/2024", wantErr: ErrInvalidSearch},
+		{name: "bad has", query: "has:photo", wantErr: ErrInvalidSearch},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			search, from, err := parseMessageSearch(test.query)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(*search, test.want) {
+				t.Errorf("search = %+v, want %+v", *search, test.want)
+			}
+			if from != test.from {
+				t.Errorf("from = %q, want %q", from, test.from)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	EventTypingStart = "typing.start"
+	EventTypingStop  = "typing.stop"
+
+	// A client repeats typing.start while the user keeps typing. Repeats
+	// within typingThrottle only extend the indicator, which expires
+	// typingTimeout after the last one.
+	typingThrottle = 3 * time.Second
+	typingTimeout  = 6 * time.Second
+)
+
+var Typing = NewTypingTracker()
+
+// TypingTracker keeps who is typing where in memory only. Indicators are
+// never persisted and disappear on their own when a client stops sending
+// them, so a client that goes away cannot leave one behind. A user typing on
+// several devices shows one indicator, which stops once none of them types.
+type TypingTracker struct {
+	mu       sync.Mutex
+	typists  map[typingKey]*typist
+	announce func(eventType string, key typingKey)
+}
+
+type typingKey struct {
+	conversationID int
+	userID         int
+}
+
+type typist struct {
+	announcedAt time.Time
+	expiry      *time.Timer
+	connections map[*websocket.Conn]bool
+}
+
+func NewTypingTracker() *TypingTracker {
+	return &TypingTracker{typists: make(map[typingKey]*typist), announce: announceTyping}
+}
+
+func (t *TypingTracker) Start(conversationID int, userID int, conn *websocket.Conn) {
+	key := typingKey{conversationID: conversationID, userID: userID}
+	now := time.Now()
+
+	t.mu.Lock()
+	current, ok := t.typists[key]
+	if ok {
+		current.connections[conn] = true
+		current.expiry.Reset(typingTimeout)
+		if now.Sub(current.announcedAt) < typingThrottle {
+			t.mu.Unlock()
+			return
+		}
+		current.announcedAt = now
+	} else {
+		current = &typist{announcedAt: now, connections: map[*websocket.Conn]bool{conn: true}}
+		current.expiry = time.AfterFunc(typingTimeout, func() { t.expire(key, current) })
+		t.typists[key] = current
+	}
+	t.mu.Unlock()
+
+	t.announce(EventTypingStart, key)
+}
+
+// Stop ends the typing of the user on the connection. The indicator stops
+// when no other connection of theirs is typing in the conversation.
+func (t *TypingTracker) Stop(conversationID int, userID int, conn *websocket.Conn) {
+	key := typingKey{conversationID: conversationID, userID: userID}
+
+	t.mu.Lock()
+	current, ok := t.typists[key]
+	stopped := false
+	if ok {
+		delete(current.connections, conn)
+		if len(current.connections) == 0 {
+			current.expiry.Stop()
+			delete(t.typists, key)
+			stopped = true
+		}
+	}
+	t.mu.Unlock()
+
+	if stopped {
+		t.announce(EventTypingStop, key)
+	}
+}
+
+// expire ends an indicator that was not renewed in time, unless a newer one
+// replaced it meanwhile.
+func (t *TypingTracker) expire(key typingKey, expired *typist) {
+	t.mu.Lock()
+	current, ok := t.typists[key]
+	if ok && current == expired {
+		delete(t.typists, key)
+	}
+	t.mu.Unlock()
+
+	if ok && current == expired {
+		t.announce(EventTypingStop, key)
+	}
+}
+
+// announceTyping goes to the other members of the conversation only, not to
+// channel readers or the typist's own devices.
+func announceTyping(eventType string, key typingKey) {
+	RecentHub.Events <- Event{
+		Type:           eventType,
+		ConversationID: key.conversationID,
+		Data:           map[string]int{"user_id": key.userID},
+		SkipUserID:     key.userID,
+	}
+}
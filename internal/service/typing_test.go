@@ -0,0 +1,78 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestTypingTracker(t *testing.T) {
+	phone, laptop := &websocket.Conn{}, &websocket.Conn{}
+
+	steps := []struct {
+		name  string
+		start bool
+		conn  *websocket.Conn
+		want  []string
+	}{
+		{name: "first start is announced", start: true, conn: phone, want: []string{EventTypingStart}},
+		{name: "repeat within the throttle", start: true, conn: phone},
+		{name: "second device within the throttle", start: true, conn: laptop},
+		{name: "one device stops", conn: phone},
+		{name: "unknown device stops", conn: &websocket.Conn{}},
+		{name: "last device stops", conn: laptop, want: []string{EventTypingStop}},
+		{name: "stop when not typing", conn: laptop},
+		{name: "starts again", start: true, conn: laptop, want: []string{EventTypingStart}},
+	}
+
+	tracker := NewTypingTracker()
+	var announced []string
+	tracker.announce = func(eventType string, key typingKey) {
+		if key != (typingKey{conversationID: 1, userID: 2}) {
+			t.Fatalf("announced %s for %+v", eventType, key)
+		}
+		announced = append(announced, eventType)
+	}
+
+	for _, step := range steps {
+		announced = nil
+		if step.start {
+			tracker.Start(1, 2, step.conn)
+		} else {
+			tracker.Stop(1, 2, step.conn)
+		}
+		if !reflect.DeepEqual(announced, step.want) {
+			t.Fatalf("%s: announced %v, want %v", step.name, announced, step.want)
+		}
+	}
+}
+
+func TestTypingTrackerExpire(t *testing.T) {
+	tracker := NewTypingTracker()
+	var announced []string
+	tracker.announce = func(eventType string, key typingKey) {
+		announced = append(announced, eventType)
+	}
+
+	conn := &websocket.Conn{}
+	key := typingKey{conversationID: 1, userID: 2}
+	tracker.Start(1, 2, conn)
+	expired := tracker.typists[key]
+
+	tracker.Stop(1, 2, conn)
+	tracker.Start(1, 2, conn)
+	tracker.expire(key, expired)
+	if _, ok := tracker.typists[key]; !ok {
+		t.Fatal("an expired indicator removed the one that replaced it")
+	}
+
+	tracker.expire(key, tracker.typists[key])
+	if _, ok := tracker.typists[key]; ok {
+		t.Fatal("the indicator did not expire")
+	}
+	want := []string{EventTypingStart, EventTypingStop, EventTypingStart, EventTypingStop}
+	if !reflect.DeepEqual(announced, want) {
+		t.Errorf("announced %v, want %v", announced, want)
+	}
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/messaging-go-service/internal/model"
+	"github.com/messaging-go-service/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	// usernameChangeCooldown is how long a user waits between username changes.
+	usernameChangeCooldown = 30 * 24 * time.Hour
+	// usernameHoldPeriod is how long a given-up username stays unavailable to
+	// anyone but its previous owner, so nobody can take over their mentions.
+	usernameHoldPeriod = 30 * 24 * time.Hour
+)
+
+var (
+	ErrUsernameTaken         = errors.New("this username is already taken")
+	ErrEmailTaken            = errors.New("this email address is already registered")
+	ErrUsernameChangeTooSoon = errors.New("the username was changed too recently")
+	ErrUsernameUnchanged     = errors.New("this is already your username")
+	ErrPasswordRequired      = errors.New("a password is required")
+)
+
+type UserService interface {
+	Register(ctx context.Context, username string, email string, password string) (*model.User, error)
+	ChangeUsername(ctx context.Context, userID int, username string) (*model.User, error)
+	GetByHandle(ctx context.Context, handle string) (*model.User, error)
+	GetUsernameHistory(ctx context.Context, userID int) ([]model.UsernameHistory, error)
+}
+
+type UserServiceImpl struct {
+	UserRepository repository.UserRepository
+}
+
+func NewUserService(userRepo repository.UserRepository) UserService {
+	return &UserServiceImpl{
+		UserRepository: userRepo,
+	}
+}
+
+// Register creates a user with a normalized username and email address and a
+// hashed password.
+func (s *UserServiceImpl) Register(ctx context.Context, username string, email string, password string) (*model.User, error) {
+	username, err := NormalizeUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	email, err = NormalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if password == "" {
+		return nil, ErrPasswordRequired
+	}
+
+	if err := s.checkAvailable(ctx, username, email, 0); err != nil {
+		return nil, err
+	}
+
+	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := model.User{
+		Username: username,
+		Email:    email,
+		Password: string(hashedPwd),
+	}
+	if err := s.UserRepository.CreateUser(ctx, &user); err != nil {
+		// Another registration took the username or email in the meantime;
+		// the unique indexes caught it, so find out which one.
+		if repository.IsDuplicateKey(err) {
+			if conflict := s.checkAvailable(ctx, username, email, 0); conflict != nil {
+				return nil, conflict
+			}
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ChangeUsername renames the user at most once per usernameChangeCooldown.
+// The old username is kept in their history and held for them for
+// usernameHoldPeriod.
+func (s *UserServiceImpl) ChangeUsername(ctx context.Context, userID int, username string) (*model.User, error) {
+	username, err := NormalizeUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.UserRepository.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if username == user.Username {
+		return nil, ErrUsernameUnchanged
+	}
+
+	now := time.Now()
+	// Changing only the case of the username does not give it up, so it
+	// skips the cooldown.
+	caseOnly := strings.EqualFold(username, user.Username)
+	if !caseOnly && user.UsernameChangedAt != nil && now.Sub(*user.UsernameChangedAt) < usernameChangeCooldown {
+		return nil, ErrUsernameChangeTooSoon
+	}
+
+	if err := s.checkAvailable(ctx, username, "", userID); err != nil {
+		return nil, err
+	}
+
+	changedAt := now
+	if caseOnly && user.UsernameChangedAt != nil {
+		changedAt = *user.UsernameChangedAt
+	}
+	if err := s.UserRepository.ChangeUsername(ctx, user, username, changedAt); err != nil {
+		if repository.IsDuplicateKey(err) {
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+
+	user.Username = username
+	user.UsernameChangedAt = &changedAt
+	return user, nil
+}
+
+// GetByHandle finds a user by their username, with or without the leading @
+// and in any case.
+func (s *UserServiceImpl) GetByHandle(ctx context.Context, handle string) (*model.User, error) {
+	username, err := NormalizeUsername(handle)
+	if err != nil {
+		if errors.Is(err, ErrUsernameReserved) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return s.UserRepository.GetUserByUsername(ctx, username)
+}
+
+func (s *UserServiceImpl) GetUsernameHistory(ctx context.Context, userID int) ([]model.UsernameHistory, error) {
+	return s.UserRepository.GetUsernameHistory(ctx, userID)
+}
+
+// checkAvailable reports whether the username is used by anyone but the
+// given user, or held for a user who recently gave it up, and whether the
+// email address, if any, is registered.
+func (s *UserServiceImpl) checkAvailable(ctx context.Context, username string, email string, userID int) error {
+	existing, err := s.UserRepository.GetUserByUsername(ctx, username)
+	if err == nil && existing.ID != userID {
+		return ErrUsernameTaken
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	held, err := s.UserRepository.IsUsernameHeld(ctx, username, userID, time.Now().Add(-usernameHoldPeriod))
+	if err != nil {
+		return err
+	}
+	if held {
+		return ErrUsernameTaken
+	}
+
+	if email == "" {
+		return nil
+	}
+	if _, err := s.UserRepository.GetUserByEmail(ctx, email); err == nil {
+		return ErrEmailTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 32
+	// maxCombiningMarks is how many combining marks, such as accents and
+	// vowel signs, one letter may carry, which keeps out stacked marks that
+	// hide the letters underneath.
+	maxCombiningMarks = 3
+)
+
+var (
+	ErrInvalidUsername  = errors.New("invalid username")
+	ErrUsernameReserved = errors.New("this username is reserved")
+	ErrInvalidEmail     = errors.New("invalid email address")
+)
+
+// reservedUsernames cannot be registered because they would pass for the
+// service itself or clash with mention keywords and search filters.
+var reservedUsernames = map[string]bool{
+	"admin": true, "administrator": true, "root": true, "system": true,
+	"support": true, "help": true, "staff": true, "moderator": true,
+	"official": true, "security": true, "api": true, "www": true,
+	"me": true, "everyone": true, "here": true, "channel": true,
+	"null": true, "undefined": true,
+}
+
+// latinLookalikes are the Cyrillic and Greek letters that look like Latin
+// ones. A name written only in them passes for a Latin name.
+var latinLookalikes = map[rune]bool{
+	'а': true, 'в': true, 'е': true, 'к': true, 'м': true, 'н': true,
+	'о': true, 'р': true, 'с': true, 'т': true, 'у': true, 'х': true,
+	'ѕ': true, 'і': true, 'ј': true, 'ԁ': true, 'ԛ': true, 'ԝ': true,
+	'һ': true, 'ү': true, 'ӏ': true,
+	'α': true, 'β': true, 'ε': true, 'ι': true, 'κ': true, 'ν': true,
+	'ο': true, 'ρ': true, 'τ': true, 'υ': true, 'χ': true,
+}
+
+// cjkScripts are written together, so a name mixing them is not suspicious.
+var cjkScripts = map[string]bool{"Han": true, "Hiragana": true, "Katakana": true, "Hangul": true, "Bopomofo": true}
+
+// likelyScripts are the scripts scriptOf tries before all the others, most
+// used first, so most letters are found without going through every table.
+var likelyScripts = []struct {
+	name  string
+	table *unicode.RangeTable
+}{
+	{"Latin", unicode.Latin}, {"Common", unicode.Common}, {"Inherited", unicode.Inherited},
+	{"Cyrillic", unicode.Cyrillic}, {"Han", unicode.Han}, {"Hiragana", unicode.Hiragana},
+	{"Katakana", unicode.Katakana}, {"Hangul", unicode.Hangul}, {"Arabic", unicode.Arabic},
+	{"Greek", unicode.Greek}, {"Devanagari", unicode.Devanagari}, {"Thai", unicode.Thai},
+	{"Hebrew", unicode.Hebrew},
+}
+
+// NormalizeUsername returns the username in NFKC form without a leading @,
+// or an error when it is not acceptable. Usernames are letters of a single
+// script, with the combining marks of that script, ASCII digits and
+// underscores, start with a letter and cannot be told apart from another name
+// only by case, so lookups ignore case.
+func NormalizeUsername(username string) (string, error) {
+	username = norm.NFKC.String(strings.TrimPrefix(strings.TrimSpace(username), "@"))
+
+	length := utf8.RuneCountInString(username)
+	if length < minUsernameLength || length > maxUsernameLength {
+		return "", fmt.Errorf("%w: it must be %d to %d characters long", ErrInvalidUsername, minUsernameLength, maxUsernameLength)
+	}
+
+	script := ""
+	// marks counts the combining marks on the last letter, or is -1 when
+	// the last character is not a letter.
+	marks := -1
+	for i, r := range username {
+		switch {
+		case unicode.IsLetter(r):
+			// Letters shared by several scripts, such as the Japanese
+			// prolonged sound mark, take the script of the other letters.
+			if letterScript := scriptOf(r); !sharedScript(letterScript) {
+				if script == "" {
+					script = letterScript
+				} else if letterScript != script {
+					return "", fmt.Errorf("%w: it cannot mix letters from different alphabets", ErrInvalidUsername)
+				}
+			}
+			marks = 0
+			continue
+		case i == 0:
+			return "", fmt.Errorf("%w: it must start with a letter", ErrInvalidUsername)
+		case unicode.Is(unicode.M, r):
+			// Marks shared by several scripts, such as the combining
+			// accents, take the script of the letter they are on.
+			markScript := scriptOf(r)
+			if marks < 0 {
+				return "", fmt.Errorf("%w: accents and other marks must follow a letter", ErrInvalidUsername)
+			}
+			if !sharedScript(markScript) && markScript != script {
+				return "", fmt.Errorf("%w: it cannot mix letters from different alphabets", ErrInvalidUsername)
+			}
+			if marks++; marks > maxCombiningMarks {
+				return "", fmt.Errorf("%w: a letter can carry at most %d marks", ErrInvalidUsername, maxCombiningMarks)
+			}
+			continue
+		case r == '_' || (r >= '0' && r <= '9'):
+		default:
+			return "", fmt.Errorf("%w: it can only contain letters, digits and underscores", ErrInvalidUsername)
+		}
+		marks = -1
+	}
+	if script == "" {
+		return "", fmt.Errorf("%w: it must contain letters of an alphabet", ErrInvalidUsername)
+	}
+
+	lowered := strings.ToLower(username)
+	if script == "Cyrillic" || script == "Greek" {
+		lookalike := true
+		for _, r := range lowered {
+			if unicode.IsLetter(r) && !latinLookalikes[r] {
+				lookalike = false
+				break
+			}
+		}
+		if lookalike {
+			return "", fmt.Errorf("%w: it looks like a name in Latin letters", ErrInvalidUsername)
+		}
+	}
+
+	if reservedUsernames[lowered] {
+		return "", ErrUsernameReserved
+	}
+	return username, nil
+}
+
+// UsernameWithSuffix appends the suffix to the username, shortening the
+// username as needed to stay within the length limit.
+func UsernameWithSuffix(username string, suffix string) string {
+	runes := []rune(username)
+	if keep := maxUsernameLength - utf8.RuneCountInString(suffix); len(runes) > keep {
+		runes = runes[:keep]
+	}
+	return string(runes) + suffix
+}
+
+// scriptOf names the script of a letter, counting the CJK scripts as one.
+func scriptOf(r rune) string {
+	name := ""
+	for _, script := range likelyScripts {
+		if unicode.Is(script.table, r) {
+			name = script.name
+			break
+		}
+	}
+	if name == "" {
+		for scriptName, table := range unicode.Scripts {
+			if unicode.Is(table, r) {
+				name = scriptName
+				break
+			}
+		}
+	}
+
+	if cjkScripts[name] {
+		return "CJK"
+	}
+	return name
+}
+
+// sharedScript reports whether letters and marks of the script are used in
+// several others, as Common and Inherited are.
+func sharedScript(name string) bool {
+	return name == "Common" || name == "Inherited"
+}
+
+// NormalizeEmail returns the trimmed address with its domain lower-cased.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return "", ErrInvalidEmail
+	}
+
+	at := strings.LastIndex(email, "@")
+	return email[:at] + strings.ToLower(email[at:]), nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeUsername(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		want     string
+		wantErr  error
+	}{
+		{name: "plain", username: "alice", want: "alice"},
+		{name: "keeps case", username: "Alice_99", want: "Alice_99"},
+		{name: "leading @ and spaces", username: "  @bob ", want: "bob"},
+		{name: "full width is folded", username: "ａｌｉｃｅ", want: "alice"},
+		{name: "shortest", username: "abc", want: "abc"},
+		{name: "longest", username: strings.Repeat("a", 32), want: strings.Repeat("a", 32)},
+		{name: "too short", username: "ab", wantErr: ErrInvalidUsername},
+		{name: "too long", username: strings.Repeat("a", 33), wantErr: ErrInvalidUsername},
+		{name: "starts with a digit", username: "1alice", wantErr: ErrInvalidUsername},
+		{name: "starts with an underscore", username: "_alice", wantErr: ErrInvalidUsername},
+		{name: "dot", username: "al.ice", wantErr: ErrInvalidUsername},
+		{name: "hyphen", username: "al-ice", wantErr: ErrInvalidUsername},
+		{name: "non-ASCII digit", username: "alice٣", wantErr: ErrInvalidUsername},
+		{name: "Cyrillic", username: "Дмитрий", want: "Дмитрий"},
+		{name: "CJK scripts together", username: "田中さん", want: "田中さん"},
+		{name: "Katakana with the shared prolonged sound mark", username: "ラーメン", want: "ラーメン"},
+		{name: "only shared letters", username: "ーーー", wantErr: ErrInvalidUsername},
+		{name: "Latin with a Cyrillic letter", username: "alicе", wantErr: ErrInvalidUsername},
+		{name: "Cyrillic that looks Latin", username: "рсо", wantErr: ErrInvalidUsername},
+		{name: "reserved", username: "Admin", wantErr: ErrUsernameReserved},
+		{name: "combining accent is composed", username: "e\u0301mile", want: "\u00e9mile"},
+		{name: "combining accent without a composed form", username: "q\u0301uinn", want: "q\u0301uinn"},
+		{name: "Devanagari vowel sign", username: "देवी", want: "देवी"},
+		{name: "Thai vowel and tone marks", username: "กิ่งแก้ว", want: "กิ่งแก้ว"},
+		{name: "mark from another script", username: "a\u0947bc", wantErr: ErrInvalidUsername},
+		{name: "mark on a digit", username: "ab1\u0301", wantErr: ErrInvalidUsername},
+		{name: "mark on an underscore", username: "ab_\u0301c", wantErr: ErrInvalidUsername},
+		{name: "most marks on one letter", username: "q\u0301\u0302\u0303rs", want: "q\u0301\u0302\u0303rs"},
+		{name: "too many marks", username: "q\u0301\u0302\u0303\u0304rs", wantErr: ErrInvalidUsername},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NormalizeUsername(test.username)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("NormalizeUsername(%q) error = %v, want %v", test.username, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeUsername(%q) unexpected error: %v", test.username, err)
+			}
+			if got != test.want {
+				t.Errorf("NormalizeUsername(%q) = %q, want %q", test.username, got, test.want)
+			}
+		})
+	}
+}
+
+func TestUsernameWithSuffix(t *testing.T) {
+	tests := []struct {
+		username string
+		suffix   string
+		want     string
+	}{
+		{username: "alice", suffix: "_5", want: "alice_5"},
+		{username: strings.Repeat("a", 32), suffix: "_5", want: strings.Repeat("a", 30) + "_5"},
+		{username: strings.Repeat("д", 32), suffix: "_12", want: strings.Repeat("д", 29) + "_12"},
+	}
+
+	for _, test := range tests {
+		if got := UsernameWithSuffix(test.username, test.suffix); got != test.want {
+			t.Errorf("UsernameWithSuffix(%q, %q) = %q, want %q", test.username, test.suffix, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		email   string
+		want    string
+		wantErr bool
+	}{
+		{email: "bob@example.com", want: "bob@example.com"},
+		{email: "  Bob.Smith@Example.COM ", want: "Bob.Smith@example.com"},
+		{email: "bob+tag@sub.example.org", want: "bob+tag@sub.example.org"},
+		{email: "", wantErr: true},
+		{email: "bob", wantErr: true},
+		{email: "bob@", wantErr: true},
+		{email: "Bob <bob@example.com>", wantErr: true},
+		{email: "bob@example.com, eve@example.com", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := NormalizeEmail(test.email)
+		if test.wantErr {
+			if !errors.Is(err, ErrInvalidEmail) {
+				t.Errorf("NormalizeEmail(%q) error = %v, want %v", test.email, err, ErrInvalidEmail)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("NormalizeEmail(%q) = %q, %v, want %q", test.email, got, err, test.want)
+		}
+	}
+}
+
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "hi @alice and @bob", want: []string{"alice", "bob"}},
+		{text: "@alice @alice", want: []string{"alice"}},
+		{text: "mail bob@example.com", want: nil},
+		{text: "@@alice", want: nil},
+		{text: "@ａｌｉｃｅ", want: []string{"alice"}},
+		{text: "cc @q\u0301uinn, @देवी.", want: []string{"q\u0301uinn", "देवी"}},
+	}
+
+	for _, test := range tests {
+		if got := ExtractMentions(test.text); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ExtractMentions(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
@@ -2,14 +2,20 @@ package service
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/messaging-go-service/config"
+	"github.com/messaging-go-service/internal/dto"
 	"github.com/messaging-go-service/internal/model"
 	"github.com/messaging-go-service/internal/repository"
+	"github.com/messaging-go-service/middleware"
 	httputil "github.com/messaging-go-service/pkg/http"
 )
 
@@ -20,29 +26,98 @@ var (
 )
 
 type Hub struct {
-	Clients    map[*websocket.Conn]int
+	Clients    map[*websocket.Conn]ClientInfo
 	Broadcast  chan MessagePayload
+	Events     chan Event
+	Direct     chan DirectPayload
 	Register   chan ClientInfo
 	Unregister chan *websocket.Conn
+	Evict      chan ClientInfo
+	Connected  chan ConnectedQuery
 }
 
 type MessagePayload struct {
-	ConversationID int    `json:"conversation_id"`
-	UserID         int    `json:"user_id"`
-	Text           string `json:"text"`
+	MessageID      int                `json:"message_id,omitempty"`
+	ConversationID int                `json:"conversation_id"`
+	UserID         int                `json:"user_id"`
+	Kind           string             `json:"kind,omitempty"`
+	Text           string             `json:"text"`
+	Media          *dto.Media         `json:"media,omitempty"`
+	SharedContact  *dto.SharedContact `json:"shared_contact,omitempty"`
+	TTLSeconds     int                `json:"ttl_seconds,omitempty"`
+	ExpiresAt      *time.Time         `json:"expires_at,omitempty"`
+}
+
+const EventConversationUpdated = "conversation.updated"
+
+// Event notifies the clients of a conversation about a change other than a
+// new message. Data depends on Type. SkipUserID keeps the event from the
+// connections of the user who caused it, and UserIDs, when set, limits it
+// to the connections of those users.
+type Event struct {
+	Type           string      `json:"type"`
+	ConversationID int         `json:"conversation_id,omitempty"`
+	Data           interface{} `json:"data"`
+	SkipUserID     int         `json:"-"`
+	UserIDs        []int       `json:"-"`
+}
+
+const (
+	ClientEventSend        = "message.send"
+	ClientEventRead        = "message.read"
+	ClientEventDelivered   = "message.delivered"
+	ClientEventTypingStart = "typing.start"
+	ClientEventTypingStop  = "typing.stop"
+	ClientEventAway        = "presence.away"
+	ClientEventActive      = "presence.active"
+	ClientEventSubscribe   = "presence.subscribe"
+)
+
+// ClientEvent is what a client sends over its connection. An event without
+// a type sends Text as a message, as clients did before events existed.
+type ClientEvent struct {
+	Type      string `json:"type"`
+	Text      string `json:"text"`
+	MessageID int    `json:"message_id"`
+	UserIDs   []int  `json:"user_ids"`
+}
+
+type ErrorPayload struct {
+	Error      string `json:"error"`
+	RetryAfter int    `json:"retry_after,omitempty"`
+}
+
+// DirectPayload is written to a single connection, such as an error reply to
+// the sender. It goes through the hub because a connection only supports one
+// concurrent writer.
+type DirectPayload struct {
+	Connection *websocket.Conn
+	Payload    interface{}
+}
+
+// ConnectedQuery asks the hub which users have a connection to a
+// conversation. The hub answers on Reply.
+type ConnectedQuery struct {
+	ConversationID int
+	Reply          chan map[int]bool
 }
 
 type ClientInfo struct {
 	Connection     *websocket.Conn
 	ConversationID int
+	UserID         int
 }
 
 func NewHub() *Hub {
 	return &Hub{
-		Clients:    make(map[*websocket.Conn]int),
+		Clients:    make(map[*websocket.Conn]ClientInfo),
 		Broadcast:  make(chan MessagePayload),
+		Events:     make(chan Event),
+		Direct:     make(chan DirectPayload),
 		Register:   make(chan ClientInfo),
 		Unregister: make(chan *websocket.Conn),
+		Evict:      make(chan ClientInfo),
+		Connected:  make(chan ConnectedQuery),
 	}
 }
 
@@ -50,22 +125,71 @@ func (h *Hub) Run() {
 	for {
 		select {
 		case client := <-h.Register:
-			h.Clients[client.Connection] = client.ConversationID
+			h.Clients[client.Connection] = client
 		case conn := <-h.Unregister:
 			if _, ok := h.Clients[conn]; ok {
 				delete(h.Clients, conn)
 				conn.Close()
 			}
 		case message := <-h.Broadcast:
-			for conn, convID := range h.Clients {
-				if convID == message.ConversationID {
-					err := conn.WriteJSON(message)
-					if err != nil {
-						h.Unregister <- conn
-						conn.Close()
-					}
+			h.send(h.recipients(message.ConversationID, 0, nil), message)
+		case event := <-h.Events:
+			h.send(h.recipients(event.ConversationID, event.SkipUserID, event.UserIDs), event)
+		case evicted := <-h.Evict:
+			// Closes the connections of a user who is no longer a member.
+			for conn, client := range h.Clients {
+				if client.ConversationID == evicted.ConversationID && client.UserID == evicted.UserID {
+					delete(h.Clients, conn)
+					conn.Close()
 				}
 			}
+		case query := <-h.Connected:
+			users := make(map[int]bool)
+			for _, client := range h.Clients {
+				if client.ConversationID == query.ConversationID {
+					users[client.UserID] = true
+				}
+			}
+			query.Reply <- users
+		case direct := <-h.Direct:
+			if _, ok := h.Clients[direct.Connection]; ok {
+				if err := direct.Connection.WriteJSON(direct.Payload); err != nil {
+					delete(h.Clients, direct.Connection)
+					direct.Connection.Close()
+				}
+			}
+		}
+	}
+}
+
+// ConnectedUsers returns the users with a connection to the conversation.
+func (h *Hub) ConnectedUsers(conversationID int) map[int]bool {
+	reply := make(chan map[int]bool, 1)
+	h.Connected <- ConnectedQuery{ConversationID: conversationID, Reply: reply}
+	return <-reply
+}
+
+// recipients returns the connections to the conversation, leaving out those
+// of skipUserID and, when userIDs is not nil, of users not in it.
+func (h *Hub) recipients(conversationID int, skipUserID int, userIDs []int) []*websocket.Conn {
+	var conns []*websocket.Conn
+	for conn, client := range h.Clients {
+		if client.ConversationID != conversationID || client.UserID == skipUserID {
+			continue
+		}
+		if userIDs != nil && !slices.Contains(userIDs, client.UserID) {
+			continue
+		}
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+func (h *Hub) send(conns []*websocket.Conn, payload interface{}) {
+	for _, conn := range conns {
+		if err := conn.WriteJSON(payload); err != nil {
+			delete(h.Clients, conn)
+			conn.Close()
 		}
 	}
 }
@@ -78,38 +202,125 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Browsers cannot set headers on a WebSocket handshake, so the token may also come as a query parameter.
+	tokenString := r.URL.Query().Get("token")
+	if authHeader := r.Header.Get("Authorization"); tokenString == "" && strings.HasPrefix(authHeader, "Bearer ") {
+		tokenString = strings.TrimPrefix(authHeader, "Bearer ")
+	}
+	userID, err := middleware.ParseToken(tokenString)
+	if err != nil {
+		httputil.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "You are not authorized"})
+		return
+	}
+
+	db := config.GetDBInstance()
+	repo := repository.NewMessageRepositoryImpl(db)
+	conversationRepo := repository.NewConversationRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	policy := NewPolicy(repository.NewBlockRepository(db), conversationRepo, userRepo, repository.NewContactRepository(db))
+	messageService := NewMessageService(repo, NewNotificationService(repository.NewNotificationRepository(db), userRepo, conversationRepo))
+	receiptService := NewReceiptService(conversationRepo, repo)
+	presenceService := NewPresenceService(userRepo, policy)
+
+	participant, err := conversationRepo.GetParticipant(Ctx, conversationID, userID)
+	if err != nil || !participant.Active() {
+		httputil.WriteResponse(w, http.StatusForbidden, map[string]string{"error": "You are not a participant of this conversation"})
+		return
+	}
+
 	conn, err := Upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		httputil.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to upgrade connection"})
 		return
 	}
 
-	RecentHub.Register <- ClientInfo{Connection: conn, ConversationID: conversationID}
+	RecentHub.Register <- ClientInfo{Connection: conn, ConversationID: conversationID, UserID: userID}
+	Presence.Connect(userID, conn)
 
 	defer func() {
+		Typing.Stop(conversationID, userID, conn)
+		lastSeenAt := time.Now()
+		if Presence.Disconnect(userID, conn, lastSeenAt) == model.PresenceOffline {
+			if err := userRepo.UpdateLastSeen(Ctx, userID, lastSeenAt); err != nil {
+				log.Println("Failed to save last seen", err)
+			}
+		}
 		RecentHub.Unregister <- conn
 	}()
 
-	repo := repository.NewMessageRepositoryImpl(config.GetDBInstance())
-
 	for {
-		var message MessagePayload
-		err := conn.ReadJSON(&message)
+		var event ClientEvent
+		err := conn.ReadJSON(&event)
 		if err != nil {
 			break
 		}
 
-		newMessage := model.Message{
-			ParticipantID: message.UserID,
-			Text:          message.Text,
+		switch event.Type {
+		case "", ClientEventSend:
+			sendClientMessage(conn, policy, messageService, participant, event.Text)
+		case ClientEventRead:
+			if _, err := receiptService.MarkRead(Ctx, conversationID, userID, event.MessageID); err != nil {
+				RecentHub.Direct <- DirectPayload{Connection: conn, Payload: ErrorPayload{Error: clientError(err, "Failed to mark read")}}
+			}
+		case ClientEventTypingStart:
+			Typing.Start(conversationID, userID, conn)
+		case ClientEventTypingStop:
+			Typing.Stop(conversationID, userID, conn)
+		case ClientEventAway, ClientEventActive:
+			Presence.SetActive(userID, conn, event.Type == ClientEventActive)
+		case ClientEventSubscribe:
+			presences, err := presenceService.Subscribe(Ctx, conn, userID, event.UserIDs)
+			if err != nil {
+				RecentHub.Direct <- DirectPayload{Connection: conn, Payload: ErrorPayload{Error: clientError(err, "Failed to subscribe to presence")}}
+				continue
+			}
+			RecentHub.Direct <- DirectPayload{Connection: conn, Payload: Event{Type: EventPresenceSnapshot, Data: dto.NewPresences(presences)}}
+		case ClientEventDelivered:
+			if err := receiptService.MarkDelivered(Ctx, conversationID, userID, event.MessageID); err != nil {
+				RecentHub.Direct <- DirectPayload{Connection: conn, Payload: ErrorPayload{Error: clientError(err, "Failed to mark delivered")}}
+			}
+		default:
+			RecentHub.Direct <- DirectPayload{Connection: conn, Payload: ErrorPayload{Error: "Unknown event type"}}
 		}
+	}
+}
 
-		err = repo.CreateMessage(Ctx, &newMessage)
-		if err != nil {
-			log.Println("Failed to save message", err)
-		}
+func sendClientMessage(conn *websocket.Conn, policy Policy, messageService MessageService, participant *model.Participant, text string) {
+	if err := policy.CanSendMessage(Ctx, participant.UserID, participant.ConversationID, text); err != nil {
+		sendClientError(conn, err, "Failed to send message")
+		return
+	}
+
+	newMessage := model.Message{
+		ConversationID: participant.ConversationID,
+		ParticipantID:  participant.ID,
+		Kind:           model.MessageKindText,
+		Text:           text,
+	}
 
-		RecentHub.Broadcast <- message
+	if err := messageService.Send(Ctx, &newMessage, participant.UserID); err != nil {
+		sendClientError(conn, err, "Failed to send message")
+		return
 	}
+	Typing.Stop(participant.ConversationID, participant.UserID, conn)
+}
 
+// sendClientError tells the connection why its request failed, with the
+// wait when it was rate limited.
+func sendClientError(conn *websocket.Conn, err error, fallback string) {
+	payload := ErrorPayload{Error: clientError(err, fallback)}
+	var rateLimited *RateLimitError
+	if errors.As(err, &rateLimited) {
+		payload.RetryAfter = rateLimited.RetryAfterSeconds()
+	}
+	RecentHub.Direct <- DirectPayload{Connection: conn, Payload: payload}
+}
+
+// clientError hides unexpected errors from the client behind fallback.
+func clientError(err error, fallback string) string {
+	if IsPolicyViolation(err) || errors.Is(err, ErrMessageNotFound) {
+		return err.Error()
+	}
+	log.Println(fallback, err)
+	return fallback
 }
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"os"
 	"strings"
@@ -8,6 +10,10 @@ import (
 	"github.com/dgrijalva/jwt-go"
 )
 
+type contextKey string
+
+const userIDKey contextKey = "user_id"
+
 func CheckAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -16,19 +22,44 @@ func CheckAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		tokenString := strings.Split(authHeader, " ")[1]
-
-		claims := &jwt.StandardClaims{}
-
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET_KEY")), nil
-		})
+		parts := strings.Split(authHeader, " ")
+		if len(parts) != 2 {
+			http.Error(w, "Token not valid", http.StatusForbidden)
+			return
+		}
 
-		if err != nil || !token.Valid {
+		userID, err := ParseToken(parts[1])
+		if err != nil {
 			http.Error(w, "Token not valid", http.StatusForbidden)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), userIDKey, userID)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// ParseToken validates an access token and returns the id of the user it was issued to.
+func ParseToken(tokenString string) (int, error) {
+	claims := jwt.MapClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("JWT_SECRET_KEY")), nil
+	})
+	if err != nil || !token.Valid {
+		return 0, errors.New("token not valid")
+	}
+
+	id, ok := claims["id"].(float64)
+	if !ok {
+		return 0, errors.New("token has no user id")
+	}
+
+	return int(id), nil
+}
+
+// GetUserID returns the id of the user authenticated by CheckAuth.
+func GetUserID(r *http.Request) (int, bool) {
+	userID, ok := r.Context().Value(userIDKey).(int)
+	return userID, ok
+}
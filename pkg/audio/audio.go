@@ -0,0 +1,66 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"time"
+)
+
+const (
+	FormatWAV    = "wav"
+	FormatOpus   = "opus"
+	FormatVorbis = "vorbis"
+
+	// WaveformBars is the number of amplitude samples kept for a voice note.
+	WaveformBars = 64
+	// WaveformMax is the value of the loudest bar in a waveform.
+	WaveformMax = 255
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported audio format")
+
+type Info struct {
+	Format   string
+	MimeType string
+	Duration time.Duration
+	Waveform []int
+}
+
+// Probe detects the container of an uploaded voice note from its magic bytes
+// and extracts its duration and a downsampled amplitude waveform.
+func Probe(data []byte) (*Info, error) {
+	switch {
+	case len(data) >= 12 && bytes.Equal(data[0:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WAVE")):
+		return probeWAV(data)
+	case len(data) >= 4 && bytes.Equal(data[0:4], []byte("OggS")):
+		return probeOgg(data)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// normalize scales bucket levels so the loudest bar equals WaveformMax.
+func normalize(levels []float64) []int {
+	peak := 0.0
+	for _, level := range levels {
+		if level > peak {
+			peak = level
+		}
+	}
+
+	waveform := make([]int, len(levels))
+	if peak == 0 {
+		return waveform
+	}
+	for i, level := range levels {
+		waveform[i] = int(level / peak * WaveformMax)
+	}
+	return waveform
+}
+
+func bucketCount(total int64) int {
+	if total < WaveformBars {
+		return int(total)
+	}
+	return WaveformBars
+}
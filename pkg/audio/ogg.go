@@ -0,0 +1,156 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+const (
+	oggHeaderSize  = 27
+	opusSampleRate = 48000
+)
+
+var ErrInvalidOgg = errors.New("invalid ogg file")
+
+type oggPacket struct {
+	size     int
+	position int64
+}
+
+// probeOgg reads an Ogg Opus (or Vorbis) stream without decoding it. The
+// duration comes from the final granule position. The waveform is estimated
+// from the size of each compressed packet: with variable bitrate encoding
+// silence compresses to a few bytes while speech does not, which is enough
+// to draw a recognizable waveform before the client downloads the file.
+func probeOgg(data []byte) (*Info, error) {
+	var packets []oggPacket
+	var pending []byte
+	var headerPackets, sampleRate int
+	var preSkip, lastGranule, prevGranule int64
+	var serial uint32
+	format := ""
+
+	offset := 0
+	for offset+oggHeaderSize <= len(data) {
+		if !bytes.Equal(data[offset:offset+4], []byte("OggS")) {
+			return nil, ErrInvalidOgg
+		}
+
+		granule := int64(binary.LittleEndian.Uint64(data[offset+6 : offset+14]))
+		pageSerial := binary.LittleEndian.Uint32(data[offset+14 : offset+18])
+		segments := int(data[offset+26])
+		if offset+oggHeaderSize+segments > len(data) {
+			return nil, ErrInvalidOgg
+		}
+		lacing := data[offset+oggHeaderSize : offset+oggHeaderSize+segments]
+
+		bodyStart := offset + oggHeaderSize + segments
+		bodySize := 0
+		for _, l := range lacing {
+			bodySize += int(l)
+		}
+		if bodyStart+bodySize > len(data) {
+			return nil, ErrInvalidOgg
+		}
+		body := data[bodyStart : bodyStart+bodySize]
+		offset = bodyStart + bodySize
+
+		if format == "" {
+			serial = pageSerial
+		} else if pageSerial != serial {
+			// Only the first logical stream is considered.
+			continue
+		}
+
+		var pagePackets []int
+		pos := 0
+		for _, l := range lacing {
+			pending = append(pending, body[pos:pos+int(l)]...)
+			pos += int(l)
+			if l == 255 {
+				continue
+			}
+
+			if format == "" {
+				switch {
+				case bytes.HasPrefix(pending, []byte("OpusHead")) && len(pending) >= 12:
+					format = FormatOpus
+					headerPackets = 2
+					sampleRate = opusSampleRate
+					preSkip = int64(binary.LittleEndian.Uint16(pending[10:12]))
+				case bytes.HasPrefix(pending, []byte("\x01vorbis")) && len(pending) >= 16:
+					format = FormatVorbis
+					headerPackets = 3
+					sampleRate = int(binary.LittleEndian.Uint32(pending[12:16]))
+				default:
+					return nil, ErrUnsupportedFormat
+				}
+			}
+
+			if headerPackets > 0 {
+				headerPackets--
+			} else {
+				pagePackets = append(pagePackets, len(pending))
+			}
+			pending = nil
+		}
+
+		// A granule of -1 means no packet finished on this page.
+		if granule < 0 || len(pagePackets) == 0 {
+			continue
+		}
+		// Granule positions never go back in a valid stream.
+		if granule < prevGranule {
+			return nil, ErrInvalidOgg
+		}
+		for i, size := range pagePackets {
+			position := prevGranule + (granule-prevGranule)*int64(i+1)/int64(len(pagePackets))
+			packets = append(packets, oggPacket{size: size, position: position})
+		}
+		prevGranule = granule
+		lastGranule = granule
+	}
+
+	if format == "" || sampleRate == 0 {
+		return nil, ErrInvalidOgg
+	}
+
+	samples := lastGranule - preSkip
+	if samples < 0 {
+		samples = 0
+	}
+
+	info := &Info{
+		Format:   format,
+		MimeType: "audio/ogg",
+		Duration: time.Duration(samples) * time.Second / time.Duration(sampleRate),
+		Waveform: []int{},
+	}
+
+	bars := bucketCount(int64(len(packets)))
+	if bars == 0 || lastGranule == 0 {
+		return info, nil
+	}
+
+	levels := make([]float64, bars)
+	counts := make([]int, bars)
+	for _, packet := range packets {
+		// Work in floats so a huge granule position cannot overflow.
+		bucket := int(float64(packet.position) / float64(lastGranule) * float64(bars))
+		if bucket >= bars {
+			bucket = bars - 1
+		}
+		levels[bucket] += float64(packet.size)
+		counts[bucket]++
+	}
+	for i := range levels {
+		if counts[i] > 0 {
+			levels[i] /= float64(counts[i])
+		}
+	}
+
+	info.Waveform = normalize(levels)
+	return info, nil
+}
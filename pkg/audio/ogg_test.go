@@ -0,0 +1,107 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+// oggPage returns one page of a single logical stream holding the given
+// packets, each small enough for a single lacing value.
+func oggPage(granule int64, packets ...[]byte) []byte {
+	page := []byte("OggS\x00\x00")
+	page = binary.LittleEndian.AppendUint64(page, uint64(granule))
+	page = binary.LittleEndian.AppendUint32(page, 1)
+	page = binary.LittleEndian.AppendUint32(page, 0)
+	page = binary.LittleEndian.AppendUint32(page, 0)
+	page = append(page, byte(len(packets)))
+	for _, packet := range packets {
+		page = append(page, byte(len(packet)))
+	}
+	for _, packet := range packets {
+		page = append(page, packet...)
+	}
+	return page
+}
+
+func opusHead(preSkip uint16) []byte {
+	head := []byte("OpusHead\x01\x01")
+	head = binary.LittleEndian.AppendUint16(head, preSkip)
+	head = binary.LittleEndian.AppendUint32(head, opusSampleRate)
+	return append(head, 0, 0, 0)
+}
+
+// opusStream returns the header pages followed by one page per granule
+// position, each holding a packet of the matching size.
+func opusStream(preSkip uint16, granules []int64, sizes []int) []byte {
+	data := oggPage(0, opusHead(preSkip))
+	data = append(data, oggPage(0, []byte("OpusTags"))...)
+	for i, granule := range granules {
+		data = append(data, oggPage(granule, make([]byte, sizes[i]))...)
+	}
+	return data
+}
+
+func TestProbeOgg(t *testing.T) {
+	// Four quiet packets then four loud ones, 20ms apiece after a pre-skip
+	// of 312 samples.
+	var granules []int64
+	var sizes []int
+	for i := 1; i <= 8; i++ {
+		granules = append(granules, 312+int64(i)*960)
+		if i <= 4 {
+			sizes = append(sizes, 3)
+		} else {
+			sizes = append(sizes, 120)
+		}
+	}
+
+	info, err := Probe(opusStream(312, granules, sizes))
+	if err != nil {
+		t.Fatalf("Probe() error = %v", err)
+	}
+	if info.Format != FormatOpus || info.MimeType != "audio/ogg" {
+		t.Errorf("format = %q %q, want opus audio/ogg", info.Format, info.MimeType)
+	}
+	if info.Duration != 160*time.Millisecond {
+		t.Errorf("Duration = %v, want 160ms", info.Duration)
+	}
+	if len(info.Waveform) != 8 {
+		t.Fatalf("len(Waveform) = %d, want 8", len(info.Waveform))
+	}
+	if info.Waveform[0] >= info.Waveform[7] || info.Waveform[7] != WaveformMax {
+		t.Errorf("Waveform = %v, want quiet start and loudest end", info.Waveform)
+	}
+}
+
+func TestProbeOggErrors(t *testing.T) {
+	valid := opusStream(0, []int64{960}, []int{10})
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"truncated page", valid[:len(valid)-1], ErrInvalidOgg},
+		{"bad capture pattern", append(append([]byte{}, valid...), []byte("junkjunkjunkjunkjunkjunkjunk")...), ErrInvalidOgg},
+		{"not opus or vorbis", oggPage(0, []byte("\x80theora")), ErrUnsupportedFormat},
+		{"granule goes back", opusStream(0, []int64{1920, 960}, []int{10, 10}), ErrInvalidOgg},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Probe(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("Probe() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeOggHugeGranule(t *testing.T) {
+	// Granule positions near the int64 limit must not overflow the
+	// waveform bucket and panic.
+	const huge = int64(1) << 62
+	if _, err := Probe(opusStream(0, []int64{huge / 2, huge}, []int{10, 10})); err != nil {
+		t.Fatalf("Probe() error = %v", err)
+	}
+}
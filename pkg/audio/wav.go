@@ -0,0 +1,153 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+var ErrInvalidWAV = errors.New("invalid wav file")
+
+type wavFormat struct {
+	audioFormat   uint16
+	channels      uint16
+	sampleRate    uint32
+	byteRate      uint32
+	blockAlign    uint16
+	bitsPerSample uint16
+}
+
+func probeWAV(data []byte) (*Info, error) {
+	var format *wavFormat
+	var samples []byte
+
+	offset := 12
+	for offset+8 <= len(data) {
+		id := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		body := data[offset+8:]
+		if size > len(body) {
+			// Streaming encoders often leave the data size unset, so use what was uploaded.
+			size = len(body)
+		}
+		body = body[:size]
+
+		switch id {
+		case "fmt ":
+			parsed, err := parseWAVFormat(body)
+			if err != nil {
+				return nil, err
+			}
+			format = parsed
+		case "data":
+			samples = body
+		}
+
+		// Chunks are padded to an even number of bytes.
+		offset += 8 + size + size%2
+	}
+
+	if format == nil || samples == nil {
+		return nil, ErrInvalidWAV
+	}
+
+	frames := int64(len(samples)) / int64(format.blockAlign)
+	info := &Info{
+		Format:   FormatWAV,
+		MimeType: "audio/wav",
+		Duration: time.Duration(frames) * time.Second / time.Duration(format.sampleRate),
+		Waveform: []int{},
+	}
+
+	bars := bucketCount(frames)
+	if bars == 0 {
+		return info, nil
+	}
+
+	levels := make([]float64, bars)
+	sampleSize := int(format.bitsPerSample / 8)
+	for frame := int64(0); frame < frames; frame++ {
+		start := int(frame) * int(format.blockAlign)
+		var amplitude float64
+		for channel := 0; channel < int(format.channels); channel++ {
+			pos := start + channel*sampleSize
+			amplitude += math.Abs(decodeSample(samples[pos:pos+sampleSize], format))
+		}
+		amplitude /= float64(format.channels)
+
+		bucket := int(frame * int64(bars) / frames)
+		if amplitude > levels[bucket] {
+			levels[bucket] = amplitude
+		}
+	}
+
+	info.Waveform = normalize(levels)
+	return info, nil
+}
+
+func parseWAVFormat(body []byte) (*wavFormat, error) {
+	if len(body) < 16 {
+		return nil, ErrInvalidWAV
+	}
+
+	format := &wavFormat{
+		audioFormat:   binary.LittleEndian.Uint16(body[0:2]),
+		channels:      binary.LittleEndian.Uint16(body[2:4]),
+		sampleRate:    binary.LittleEndian.Uint32(body[4:8]),
+		byteRate:      binary.LittleEndian.Uint32(body[8:12]),
+		blockAlign:    binary.LittleEndian.Uint16(body[12:14]),
+		bitsPerSample: binary.LittleEndian.Uint16(body[14:16]),
+	}
+
+	if format.audioFormat == wavFormatExtensible {
+		// The real format code is the first two bytes of the sub-format GUID.
+		if len(body) < 26 {
+			return nil, ErrInvalidWAV
+		}
+		format.audioFormat = binary.LittleEndian.Uint16(body[24:26])
+	}
+
+	if format.channels == 0 || format.sampleRate == 0 {
+		return nil, ErrInvalidWAV
+	}
+
+	switch {
+	case format.audioFormat == wavFormatPCM && (format.bitsPerSample == 8 || format.bitsPerSample == 16 || format.bitsPerSample == 24 || format.bitsPerSample == 32):
+	case format.audioFormat == wavFormatFloat && format.bitsPerSample == 32:
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+
+	if int(format.blockAlign) < int(format.channels)*int(format.bitsPerSample/8) {
+		return nil, ErrInvalidWAV
+	}
+
+	return format, nil
+}
+
+// decodeSample converts one little-endian sample to the range [-1, 1].
+func decodeSample(b []byte, format *wavFormat) float64 {
+	switch format.bitsPerSample {
+	case 8:
+		// 8-bit PCM is the only unsigned encoding.
+		return (float64(b[0]) - 128) / 128
+	case 16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / math.MaxInt16
+	case 24:
+		v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+		return float64(v) / (1 << 23)
+	default:
+		bits := binary.LittleEndian.Uint32(b)
+		if format.audioFormat == wavFormatFloat {
+			return float64(math.Float32frombits(bits))
+		}
+		return float64(int32(bits)) / math.MaxInt32
+	}
+}
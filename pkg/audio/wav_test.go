@@ -0,0 +1,111 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// buildWAV returns a mono 16-bit PCM file holding the given samples.
+func buildWAV(sampleRate uint32, samples []int16) []byte {
+	data := make([]byte, 0, 44+2*len(samples))
+	data = append(data, "RIFF"...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(36+2*len(samples)))
+	data = append(data, "WAVE"...)
+
+	data = append(data, "fmt "...)
+	data = binary.LittleEndian.AppendUint32(data, 16)
+	data = binary.LittleEndian.AppendUint16(data, wavFormatPCM)
+	data = binary.LittleEndian.AppendUint16(data, 1)
+	data = binary.LittleEndian.AppendUint32(data, sampleRate)
+	data = binary.LittleEndian.AppendUint32(data, sampleRate*2)
+	data = binary.LittleEndian.AppendUint16(data, 2)
+	data = binary.LittleEndian.AppendUint16(data, 16)
+
+	data = append(data, "data"...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(2*len(samples)))
+	for _, sample := range samples {
+		data = binary.LittleEndian.AppendUint16(data, uint16(sample))
+	}
+	return data
+}
+
+func TestProbeWAV(t *testing.T) {
+	// One second of silence followed by one second at full scale.
+	samples := make([]int16, 16000)
+	for i := 8000; i < len(samples); i++ {
+		samples[i] = math.MaxInt16
+	}
+
+	info, err := Probe(buildWAV(8000, samples))
+	if err != nil {
+		t.Fatalf("Probe() error = %v", err)
+	}
+	if info.Format != FormatWAV || info.MimeType != "audio/wav" {
+		t.Errorf("format = %q %q, want wav audio/wav", info.Format, info.MimeType)
+	}
+	if info.Duration != 2*time.Second {
+		t.Errorf("Duration = %v, want 2s", info.Duration)
+	}
+	if len(info.Waveform) != WaveformBars {
+		t.Fatalf("len(Waveform) = %d, want %d", len(info.Waveform), WaveformBars)
+	}
+	if info.Waveform[0] != 0 || info.Waveform[WaveformBars-1] != WaveformMax {
+		t.Errorf("Waveform starts at %d and ends at %d, want 0 and %d", info.Waveform[0], info.Waveform[WaveformBars-1], WaveformMax)
+	}
+}
+
+func TestProbeWAVErrors(t *testing.T) {
+	valid := buildWAV(8000, make([]int16, 100))
+
+	noData := append([]byte{}, valid[:36]...)
+
+	zeroRate := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint32(zeroRate[24:28], 0)
+
+	adpcm := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint16(adpcm[20:22], 2)
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty", nil, ErrUnsupportedFormat},
+		{"unknown container", []byte("ID3\x03 not audio"), ErrUnsupportedFormat},
+		{"missing data chunk", noData, ErrInvalidWAV},
+		{"zero sample rate", zeroRate, ErrInvalidWAV},
+		{"compressed", adpcm, ErrUnsupportedFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Probe(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("Probe() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeSample(t *testing.T) {
+	tests := []struct {
+		name   string
+		format wavFormat
+		bytes  []byte
+		want   float64
+	}{
+		{"8-bit silence", wavFormat{audioFormat: wavFormatPCM, bitsPerSample: 8}, []byte{128}, 0},
+		{"8-bit minimum", wavFormat{audioFormat: wavFormatPCM, bitsPerSample: 8}, []byte{0}, -1},
+		{"16-bit maximum", wavFormat{audioFormat: wavFormatPCM, bitsPerSample: 16}, []byte{0xff, 0x7f}, 1},
+		{"24-bit minimum", wavFormat{audioFormat: wavFormatPCM, bitsPerSample: 24}, []byte{0, 0, 0x80}, -1},
+		{"float half", wavFormat{audioFormat: wavFormatFloat, bitsPerSample: 32}, binary.LittleEndian.AppendUint32(nil, math.Float32bits(0.5)), 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeSample(tt.bytes, &tt.format); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("decodeSample() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package httputil
+
+import (
+	"net/http"
+	"strconv"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+type Pagination struct {
+	Page  int   `json:"page"`
+	Limit int   `json:"limit"`
+	Total int64 `json:"total"`
+}
+
+// ReadPagination reads the page and limit query parameters, falling back to
+// the defaults when they are missing or out of range.
+func ReadPagination(r *http.Request) Pagination {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	return Pagination{Page: page, Limit: limit}
+}
+
+func (p Pagination) Offset() int {
+	return (p.Page - 1) * p.Limit
+}
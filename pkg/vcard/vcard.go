@@ -0,0 +1,244 @@
+package vcard
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	Version3 = "3.0"
+	Version4 = "4.0"
+
+	// UserIDProperty links a card to a registered user of this service so an
+	// exported card can be imported again without losing the reference.
+	UserIDProperty = "X-MESSAGING-USER-ID"
+
+	maxLineLength = 75
+)
+
+var (
+	ErrInvalidCard        = errors.New("invalid vcard")
+	ErrUnsupportedVersion = errors.New("unsupported vcard version")
+)
+
+type Card struct {
+	Version      string
+	FullName     string
+	FamilyName   string
+	GivenName    string
+	Phones       []string
+	Emails       []string
+	Organization string
+	UserID       int
+}
+
+// Parse reads the first vCard 3.0 or 4.0 in data.
+func Parse(data string) (*Card, error) {
+	var card *Card
+	ended := false
+
+	for _, line := range unfold(data) {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		name, params, value, ok := splitLine(line)
+		if !ok {
+			return nil, ErrInvalidCard
+		}
+
+		if card == nil {
+			if name != "BEGIN" || !strings.EqualFold(value, "VCARD") {
+				return nil, ErrInvalidCard
+			}
+			card = &Card{}
+			continue
+		}
+
+		switch name {
+		case "END":
+			ended = true
+		case "VERSION":
+			card.Version = value
+		case "FN":
+			card.FullName = unescape(value)
+		case "N":
+			parts := splitComponents(value)
+			card.FamilyName = unescape(parts[0])
+			if len(parts) > 1 {
+				card.GivenName = unescape(parts[1])
+			}
+		case "TEL":
+			phone := unescape(value)
+			if strings.EqualFold(params["VALUE"], "uri") || strings.HasPrefix(strings.ToLower(phone), "tel:") {
+				phone = phone[strings.Index(phone, ":")+1:]
+			}
+			if phone != "" {
+				card.Phones = append(card.Phones, phone)
+			}
+		case "EMAIL":
+			if email := unescape(value); email != "" {
+				card.Emails = append(card.Emails, email)
+			}
+		case "ORG":
+			card.Organization = unescape(splitComponents(value)[0])
+		case UserIDProperty:
+			if id, err := strconv.Atoi(value); err == nil {
+				card.UserID = id
+			}
+		}
+
+		if ended {
+			break
+		}
+	}
+
+	if card == nil || !ended {
+		return nil, ErrInvalidCard
+	}
+	if card.Version != Version3 && card.Version != Version4 {
+		return nil, ErrUnsupportedVersion
+	}
+	if card.FullName == "" {
+		card.FullName = strings.TrimSpace(card.GivenName + " " + card.FamilyName)
+	}
+	if card.FullName == "" {
+		return nil, ErrInvalidCard
+	}
+
+	return card, nil
+}
+
+// Encode renders the card as a vCard of the given version.
+func (c *Card) Encode(version string) string {
+	if version != Version4 {
+		version = Version3
+	}
+
+	var lines []string
+	lines = append(lines, "BEGIN:VCARD", "VERSION:"+version)
+	lines = append(lines, "FN:"+escape(c.FullName))
+	lines = append(lines, "N:"+escape(c.FamilyName)+";"+escape(c.GivenName)+";;;")
+	for _, phone := range c.Phones {
+		if version == Version4 {
+			// URI values are not escaped, so line breaks are dropped instead.
+			lines = append(lines, "TEL;VALUE=uri:tel:"+lineBreaks.Replace(phone))
+		} else {
+			lines = append(lines, "TEL;TYPE=CELL:"+escape(phone))
+		}
+	}
+	for _, email := range c.Emails {
+		lines = append(lines, "EMAIL:"+escape(email))
+	}
+	if c.Organization != "" {
+		lines = append(lines, "ORG:"+escape(c.Organization))
+	}
+	if c.UserID != 0 {
+		lines = append(lines, UserIDProperty+":"+strconv.Itoa(c.UserID))
+	}
+	lines = append(lines, "END:VCARD")
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(fold(line))
+		b.WriteString("\r\n")
+	}
+	return b.String()
+}
+
+func unfold(data string) []string {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	var lines []string
+	for _, line := range strings.Split(data, "\n") {
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// fold splits a content line into 75 octet chunks without breaking UTF-8 sequences.
+func fold(line string) string {
+	var b strings.Builder
+	length := 0
+	for _, r := range line {
+		size := len(string(r))
+		if length+size > maxLineLength {
+			b.WriteString("\r\n ")
+			length = 1
+		}
+		b.WriteRune(r)
+		length += size
+	}
+	return b.String()
+}
+
+func splitLine(line string) (string, map[string]string, string, bool) {
+	inQuotes := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		}
+		if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon <= 0 {
+		return "", nil, "", false
+	}
+
+	segments := strings.Split(line[:colon], ";")
+	name := strings.ToUpper(segments[0])
+	if dot := strings.LastIndex(name, "."); dot >= 0 {
+		name = name[dot+1:]
+	}
+
+	params := make(map[string]string)
+	for _, segment := range segments[1:] {
+		key, value, found := strings.Cut(segment, "=")
+		if !found {
+			// vCard 2.1 style bare parameters are treated as types.
+			key, value = "TYPE", segment
+		}
+		params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+
+	return name, params, line[colon+1:], true
+}
+
+func splitComponents(value string) []string {
+	var parts []string
+	var current strings.Builder
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			current.WriteRune('\\')
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ';':
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	return append(parts, current.String())
+}
+
+var lineBreaks = strings.NewReplacer("\r", "", "\n", "")
+
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\r\n", `\n`, "\r", `\n`, "\n", `\n`, ",", `\,`, ";", `\;`).Replace(value)
+}
+
+func unescape(value string) string {
+	return strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";").Replace(value)
+}
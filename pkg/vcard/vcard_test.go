@@ -0,0 +1,144 @@
+package vcard
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want Card
+	}{
+		{
+			name: "version 3",
+			data: "BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Jane Doe\r\nN:Doe;Jane;;;\r\nTEL;TYPE=CELL:+1 555 0100\r\nEMAIL:jane@example.com\r\nORG:Acme\\, Inc.;Sales\r\nEND:VCARD\r\n",
+			want: Card{Version: Version3, FullName: "Jane Doe", FamilyName: "Doe", GivenName: "Jane", Phones: []string{"+1 555 0100"}, Emails: []string{"jane@example.com"}, Organization: "Acme, Inc."},
+		},
+		{
+			name: "version 4 tel uri",
+			data: "BEGIN:VCARD\nVERSION:4.0\nFN:Jane\nTEL;VALUE=uri:tel:+15550100\nEND:VCARD\n",
+			want: Card{Version: Version4, FullName: "Jane", Phones: []string{"+15550100"}},
+		},
+		{
+			name: "folded line and grouped property",
+			data: "BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Jane Ver\r\n y Long Name\r\nitem1.EMAIL:jane@example.com\r\nEND:VCARD\r\n",
+			want: Card{Version: Version3, FullName: "Jane Very Long Name", Emails: []string{"jane@example.com"}},
+		},
+		{
+			name: "full name from structured name",
+			data: "BEGIN:VCARD\r\nVERSION:3.0\r\nN:Doe;Jane;;;\r\nEND:VCARD\r\n",
+			want: Card{Version: Version3, FullName: "Jane Doe", FamilyName: "Doe", GivenName: "Jane"},
+		},
+		{
+			name: "user id",
+			data: "BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Jane\r\nX-MESSAGING-USER-ID:42\r\nEND:VCARD\r\n",
+			want: Card{Version: Version3, FullName: "Jane", UserID: 42},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card, err := Parse(tt.data)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(*card, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", *card, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want error
+	}{
+		{"empty", "", ErrInvalidCard},
+		{"not a vcard", "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n", ErrInvalidCard},
+		{"no end", "BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Jane\r\n", ErrInvalidCard},
+		{"no name", "BEGIN:VCARD\r\nVERSION:3.0\r\nEMAIL:jane@example.com\r\nEND:VCARD\r\n", ErrInvalidCard},
+		{"version 2.1", "BEGIN:VCARD\r\nVERSION:2.1\r\nFN:Jane\r\nEND:VCARD\r\n", ErrUnsupportedVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	card := Card{
+		FullName:     "Jane; \"JD\" Doe, Jr.",
+		FamilyName:   "Doe",
+		GivenName:    "Jane",
+		Phones:       []string{"+15550100"},
+		Emails:       []string{"jane@example.com"},
+		Organization: strings.Repeat("Ünïcödé ", 20),
+		UserID:       7,
+	}
+
+	for _, version := range []string{Version3, Version4} {
+		t.Run(version, func(t *testing.T) {
+			encoded := card.Encode(version)
+			for _, line := range strings.Split(encoded, "\r\n") {
+				if len(line) > maxLineLength {
+					t.Errorf("line longer than %d octets: %q", maxLineLength, line)
+				}
+			}
+
+			parsed, err := Parse(encoded)
+			if err != nil {
+				t.Fatalf("Parse(Encode()) error = %v", err)
+			}
+			want := card
+			want.Version = version
+			if !reflect.DeepEqual(*parsed, want) {
+				t.Errorf("Parse(Encode()) = %+v, want %+v", *parsed, want)
+			}
+		})
+	}
+}
+
+func TestEncodeLineBreaks(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"escaped newline in tel uri", "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Jane\r\nTEL;VALUE=uri:tel:+15550100\\nX-MESSAGING-USER-ID:1\r\nEND:VCARD\r\n"},
+		{"escaped newline in full name", "BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Jane\\nX-MESSAGING-USER-ID:1\r\nEND:VCARD\r\n"},
+		{"carriage return in full name", "BEGIN:VCARD\nVERSION:3.0\nFN:Jane\rX-MESSAGING-USER-ID:1\nEND:VCARD\n"},
+		{"carriage return in email", "BEGIN:VCARD\nVERSION:3.0\nFN:Jane\nEMAIL:jane@example.com\rX-MESSAGING-USER-ID:1\nEND:VCARD\n"},
+	}
+	for _, tt := range tests {
+		for _, version := range []string{Version3, Version4} {
+			t.Run(tt.name+" "+version, func(t *testing.T) {
+				card, err := Parse(tt.data)
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+
+				encoded := card.Encode(version)
+				for _, line := range strings.Split(strings.TrimSuffix(encoded, "\r\n"), "\r\n") {
+					if strings.ContainsAny(line, "\r\n") {
+						t.Errorf("line break inside content line %q", line)
+					}
+				}
+
+				parsed, err := Parse(encoded)
+				if err != nil {
+					t.Fatalf("Parse(Encode()) error = %v", err)
+				}
+				if parsed.UserID != 0 {
+					t.Errorf("Parse(Encode()) UserID = %d, want 0", parsed.UserID)
+				}
+			})
+		}
+	}
+}